@@ -0,0 +1,116 @@
+package xgp
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/MaxHalford/xgp/op"
+)
+
+// A cacheEntry stores the outcome of the evaluation of the Operator of a
+// Program or of the Operators of a MultiProgram.
+type cacheEntry struct {
+	hash      uint64
+	ops       []op.Operator
+	fitness   float64
+	residuals []float64
+	scaling   *Scaling
+}
+
+// A fitnessCache stores the fitnesses of the most recently evaluated
+// Operators, which avoids evaluating identical Programs or MultiPrograms more
+// than once. The
+// least recently used entry is evicted once the cache is full. It is safe to
+// use the cache from multiple goroutines.
+type fitnessCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[uint64]*list.Element
+	order   *list.List
+	hits    uint64
+	misses  uint64
+}
+
+func newFitnessCache(size int) *fitnessCache {
+	return &fitnessCache{
+		size:    size,
+		entries: make(map[uint64]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// get returns the entry of Operators whose hash has already been computed.
+// The Operators are compared with op.Equal in case of a hash collision.
+func (c *fitnessCache) get(hash uint64, ops ...op.Operator) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[hash]; ok {
+		var entry = el.Value.(cacheEntry)
+		if equalOps(entry.ops, ops) {
+			c.order.MoveToFront(el)
+			c.hits++
+			return entry, true
+		}
+	}
+	c.misses++
+	return cacheEntry{}, false
+}
+
+// equalOps indicates if two lists of Operators are identical.
+func equalOps(a, b []op.Operator) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !op.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hashOps combines the hashes of the Operators of a MultiProgram.
+func hashOps(ops []op.Operator) uint64 {
+	var hash uint64 = 14695981039346656037
+	for _, operator := range ops {
+		hash ^= op.Hash(operator)
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+// add inserts an entry into the cache, evicting the least recently used entry
+// if the cache is full.
+func (c *fitnessCache) add(entry cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[entry.hash]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	if c.order.Len() >= c.size {
+		var oldest = c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(cacheEntry).hash)
+	}
+	c.entries[entry.hash] = c.order.PushFront(entry)
+}
+
+// reset removes all the entries from the cache, the hit rate is kept.
+func (c *fitnessCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[uint64]*list.Element, c.size)
+	c.order.Init()
+}
+
+// hitRate returns the proportion of lookups which found an entry.
+func (c *fitnessCache) hitRate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hits+c.misses == 0 {
+		return 0
+	}
+	return float64(c.hits) / float64(c.hits+c.misses)
+}
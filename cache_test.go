@@ -0,0 +1,266 @@
+package xgp
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MaxHalford/xgp/metrics"
+	"github.com/MaxHalford/xgp/op"
+)
+
+func TestFitnessCache(t *testing.T) {
+	var (
+		cache = newFitnessCache(2)
+		ops   = []op.Operator{
+			op.Var{Index: 0},
+			op.Add{op.Var{Index: 0}, op.Const{Value: 1}},
+			op.Mul{op.Var{Index: 0}, op.Const{Value: 2}},
+		}
+	)
+	for i, operator := range ops[:2] {
+		cache.add(cacheEntry{hash: op.Hash(operator), ops: []op.Operator{operator}, fitness: float64(i)})
+	}
+	// Access the first entry so that the second one is the least recently used
+	if entry, ok := cache.get(op.Hash(ops[0]), ops[0]); !ok || entry.fitness != 0 {
+		t.Errorf("Expected a hit with fitness 0, got %t and %f", ok, entry.fitness)
+	}
+	cache.add(cacheEntry{hash: op.Hash(ops[2]), ops: []op.Operator{ops[2]}, fitness: 2})
+	if _, ok := cache.get(op.Hash(ops[1]), ops[1]); ok {
+		t.Error("Expected the least recently used entry to have been evicted")
+	}
+	if _, ok := cache.get(op.Hash(ops[2]), ops[2]); !ok {
+		t.Error("Expected a hit, got a miss")
+	}
+	// A hash collision is not a hit
+	if _, ok := cache.get(op.Hash(ops[2]), ops[0]); ok {
+		t.Error("Expected a miss, got a hit")
+	}
+	if rate := cache.hitRate(); rate != 0.5 {
+		t.Errorf("Expected a hit rate of 0.5, got %f", rate)
+	}
+}
+
+func TestGPCache(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y     = []float64{7, 13, 21, 31, 43}
+		progs = make([]Program, 2)
+		evals = make([]uint64, 2)
+	)
+	for i, cacheSize := range []uint{0, 1000} {
+		var conf = NewDefaultGPConfig()
+		conf.RNG = rand.New(rand.NewSource(42))
+		conf.NIndividuals = 20
+		conf.NGenerations = 10
+		conf.PolishBest = false
+		conf.CacheSize = cacheSize
+		var gp, err = conf.NewGP()
+		if err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		if progs[i], err = gp.BestProgram(); err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		evals[i] = gp.NEvaluations()
+		// The hit rate is only displayed if there is a cache
+		if strings.Contains(gp.progress(time.Now()), "cache hits") != (cacheSize > 0) {
+			t.Errorf("Unexpected progress %s", gp.progress(time.Now()))
+		}
+	}
+	// The cache doesn't change the outcome but saves evaluations
+	if progs[0].String() != progs[1].String() {
+		t.Errorf("Expected %s, got %s", progs[0], progs[1])
+	}
+	if evals[1] >= evals[0] {
+		t.Errorf("Expected less than %d evaluations, got %d", evals[0], evals[1])
+	}
+}
+
+func TestGPSubtreeCache(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y     = []float64{7, 13, 21, 31, 43}
+		progs = make([]Program, 2)
+	)
+	for i, mb := range []uint{0, 1} {
+		var conf = NewDefaultGPConfig()
+		conf.RNG = rand.New(rand.NewSource(42))
+		conf.NIndividuals = 20
+		conf.NGenerations = 10
+		conf.PolishBest = false
+		conf.SubtreeCacheMB = mb
+		var gp, err = conf.NewGP()
+		if err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		if progs[i], err = gp.BestProgram(); err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+	}
+	// The memoization doesn't change the outcome
+	if progs[0].String() != progs[1].String() {
+		t.Errorf("Expected %s, got %s", progs[0], progs[1])
+	}
+}
+
+// scaleOp multiplies it's operand by a factor. It holds a slice so that it
+// isn't comparable with ==.
+type scaleOp struct {
+	Factor []float64
+	Op     op.Operator
+}
+
+func (s scaleOp) Eval(X [][]float64) []float64 {
+	x := s.Op.Eval(X)
+	for i := range x {
+		x[i] *= s.Factor[0]
+	}
+	return x
+}
+
+func (s scaleOp) Arity() uint {
+	return 1
+}
+
+func (s scaleOp) Operand(i uint) op.Operator {
+	if i == 0 {
+		return s.Op
+	}
+	return nil
+}
+
+func (s scaleOp) SetOperand(i uint, operand op.Operator) op.Operator {
+	if i == 0 {
+		s.Op = operand
+	}
+	return s
+}
+
+func (s scaleOp) Simplify() op.Operator {
+	s.Op = s.Op.Simplify()
+	return s
+}
+
+func (s scaleOp) Diff(i uint) op.Operator {
+	return scaleOp{s.Factor, s.Op.Diff(i)}
+}
+
+func (s scaleOp) Name() string {
+	return "scale"
+}
+
+func (s scaleOp) String() string {
+	return fmt.Sprintf("scale(%s, %v)", s.Op, s.Factor)
+}
+
+func TestGPCacheNonComparable(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y    = []float64{7, 13, 21, 31, 43}
+		seed = op.Add{scaleOp{[]float64{2}, op.Mul{op.Var{Index: 0}, op.Var{Index: 1}}}, op.Var{Index: 1}}
+	)
+	var conf = NewDefaultGPConfig()
+	conf.RNG = rand.New(rand.NewSource(42))
+	conf.NIndividuals = 20
+	conf.NGenerations = 3
+	conf.PolishBest = false
+	conf.CacheSize = 1000
+	conf.SubtreeCacheMB = 1
+	// The same seed is evaluated twice so that it is found in the caches
+	conf.Seeds = []op.Operator{seed, seed}
+	var gp, err = conf.NewGP()
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+	}
+}
+
+func TestGPMultiClassCaches(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5, 6},
+			[]float64{6, 5, 4, 3, 2, 1},
+		}
+		Y         = []float64{0, 0, 1, 1, 2, 2}
+		testCases = []struct {
+			cacheSize uint
+			mb        uint
+			float32   bool
+		}{
+			{cacheSize: 0, mb: 0, float32: false},
+			{cacheSize: 1000, mb: 0, float32: false},
+			{cacheSize: 0, mb: 1, float32: false},
+			{cacheSize: 1000, mb: 0, float32: true},
+		}
+		progs = make([]MultiProgram, len(testCases))
+		evals = make([]uint64, len(testCases))
+	)
+	for i, tc := range testCases {
+		var conf = NewDefaultGPConfig()
+		conf.LossMetric = metrics.LogLoss{}
+		conf.RNG = rand.New(rand.NewSource(42))
+		conf.NIndividuals = 20
+		conf.NGenerations = 10
+		conf.MinHeight = 1
+		conf.MaxHeight = 3
+		conf.PolishBest = false
+		conf.CacheSize = tc.cacheSize
+		conf.SubtreeCacheMB = tc.mb
+		conf.Float32 = tc.float32
+		var gp, err = conf.NewGP()
+		if err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		if progs[i], err = gp.BestMultiProgram(); err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		evals[i] = gp.NEvaluations()
+		// The hit rate is only displayed if there is a cache
+		if strings.Contains(gp.progress(time.Now()), "cache hits") != (tc.cacheSize > 0) {
+			t.Errorf("Unexpected progress %s", gp.progress(time.Now()))
+		}
+	}
+	// Neither cache changes the outcome but the fitness cache saves
+	// evaluations
+	for _, i := range []int{1, 2} {
+		if fmt.Sprint(progs[i].Ops) != fmt.Sprint(progs[0].Ops) {
+			t.Errorf("Expected %v, got %v", progs[0].Ops, progs[i].Ops)
+		}
+	}
+	if evals[1] >= evals[0] {
+		t.Errorf("Expected less than %d evaluations, got %d", evals[0], evals[1])
+	}
+}
@@ -0,0 +1,322 @@
+package xgp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/MaxHalford/eaopt"
+
+	"github.com/MaxHalford/xgp/op"
+)
+
+// trackedSource is a rand.Source64 that counts the number of values it has
+// produced. The seed and the count are enough to restore it's state.
+type trackedSource struct {
+	seed  int64
+	draws uint64
+	src   rand.Source64
+}
+
+func newTrackedSource(seed int64) *trackedSource {
+	return &trackedSource{
+		seed: seed,
+		src:  rand.NewSource(seed).(rand.Source64),
+	}
+}
+
+func (s *trackedSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *trackedSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *trackedSource) Seed(seed int64) {
+	s.seed = seed
+	s.draws = 0
+	s.src.Seed(seed)
+}
+
+type rngState struct {
+	Seed  int64  `json:"seed"`
+	Draws uint64 `json:"draws"`
+}
+
+func (s trackedSource) state() rngState {
+	return rngState{Seed: s.seed, Draws: s.draws}
+}
+
+// newTrackedRand returns a random number generator in a given state by
+// replaying the values it has already produced.
+func newTrackedRand(state rngState) (*rand.Rand, *trackedSource) {
+	var src = newTrackedSource(state.Seed)
+	for src.draws < state.Draws {
+		src.Uint64()
+	}
+	return rand.New(src), src
+}
+
+// trackRNGs replaces the random number generators of the GP and of each
+// population with ones which can be checkpointed. The new generators are
+// seeded from the GA's random number generator so that the results remain
+// reproducible.
+func (gp *GP) trackRNGs(ga *eaopt.GA) {
+	gp.popSources = make([]*trackedSource, len(ga.Populations))
+	for i := range ga.Populations {
+		ga.Populations[i].RNG, gp.popSources[i] = newTrackedRand(rngState{Seed: ga.RNG.Int63()})
+	}
+	gp.RNG, gp.rngSource = newTrackedRand(rngState{Seed: ga.RNG.Int63()})
+	ga.RNG = gp.RNG
+}
+
+// A jsonFloat is a float64 which can be serialized even if it isn't finite,
+// in which case it is written as a string.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	var x = float64(f)
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return json.Marshal(strconv.FormatFloat(x, 'g', -1, 64))
+	}
+	return json.Marshal(x)
+}
+
+func (f *jsonFloat) UnmarshalJSON(bytes []byte) error {
+	var (
+		s string
+		x float64
+	)
+	if err := json.Unmarshal(bytes, &s); err == nil {
+		x, err = strconv.ParseFloat(s, 64)
+		*f = jsonFloat(x)
+		return err
+	}
+	err := json.Unmarshal(bytes, &x)
+	*f = jsonFloat(x)
+	return err
+}
+
+type checkpointIndividual struct {
+	ID      string        `json:"id"`
+	Ops     []op.SerialOp `json:"ops"`
+	Fitness *jsonFloat    `json:"fitness,omitempty"`
+}
+
+type checkpointPopulation struct {
+	ID          string                 `json:"id"`
+	Age         time.Duration          `json:"age"`
+	Generations uint                   `json:"generations"`
+	RNG         rngState               `json:"rng"`
+	Individuals []checkpointIndividual `json:"individuals"`
+}
+
+type checkpointBestValidation struct {
+	Score      jsonFloat            `json:"score"`
+	Generation uint                 `json:"generation"`
+	Individual checkpointIndividual `json:"individual"`
+}
+
+// A checkpoint contains the state of the GA after a given number of
+// generations, along with the state of the GP which is needed to resume in
+// the same way as if there had been no interruption. The fitnesses are stored
+// because they can't always be recomputed, for instance with Baldwinian
+// learning. Rows contains the training rows on which the generation was
+// evaluated, it is nil if all the rows were used.
+type checkpoint struct {
+	Generations    uint                      `json:"generations"`
+	Age            time.Duration             `json:"age"`
+	RNG            rngState                  `json:"rng"`
+	Populations    []checkpointPopulation    `json:"populations"`
+	HallOfFame     []checkpointIndividual    `json:"hall_of_fame"`
+	NEvaluations   uint64                    `json:"n_evaluations"`
+	Rows           []int                     `json:"rows,omitempty"`
+	BestValidation *checkpointBestValidation `json:"best_validation,omitempty"`
+}
+
+func newCheckpointIndividual(indi eaopt.Individual) checkpointIndividual {
+	var ops []op.Operator
+	switch genome := indi.Genome.(type) {
+	case *Program:
+		ops = []op.Operator{genome.Op}
+	case *MultiProgram:
+		ops = genome.Ops
+	}
+	var serials = make([]op.SerialOp, len(ops))
+	for i, operator := range ops {
+		serials[i] = op.SerializeOp(operator)
+	}
+	var ci = checkpointIndividual{ID: indi.ID, Ops: serials}
+	if indi.Genome != nil {
+		var fitness = jsonFloat(indi.Fitness)
+		ci.Fitness = &fitness
+	}
+	return ci
+}
+
+// individual parses a checkpointIndividual and evaluates it, which restores
+// the state of it's Genome. The stored fitness is then used if there is one.
+// Empty individuals are placeholders for hall of fame members which have not
+// been found yet.
+func (gp *GP) individual(ci checkpointIndividual) (eaopt.Individual, error) {
+	if len(ci.Ops) == 0 {
+		return eaopt.Individual{Fitness: math.Inf(1), ID: ci.ID}, nil
+	}
+	var ops = make([]op.Operator, len(ci.Ops))
+	for i, serial := range ci.Ops {
+		operator, err := op.ParseOp(serial)
+		if err != nil {
+			return eaopt.Individual{}, err
+		}
+		ops[i] = operator
+	}
+	var genome eaopt.Genome
+	if gp.multiClass() {
+		if len(ops) != gp.nClasses {
+			return eaopt.Individual{}, fmt.Errorf("Individual %s has %d programs but there are %d classes",
+				ci.ID, len(ops), gp.nClasses)
+		}
+		genome = &MultiProgram{GP: gp, Ops: ops, Classes: gp.classes}
+	} else {
+		if len(ops) != 1 {
+			return eaopt.Individual{}, fmt.Errorf("Individual %s has %d programs instead of 1", ci.ID, len(ops))
+		}
+		genome = &Program{GP: gp, Op: ops[0]}
+	}
+	var indi = eaopt.Individual{Genome: genome, ID: ci.ID}
+	if err := indi.Evaluate(); err != nil {
+		return eaopt.Individual{}, err
+	}
+	if ci.Fitness != nil {
+		indi.Fitness = float64(*ci.Fitness)
+	}
+	return indi, nil
+}
+
+// saveCheckpoint writes the state of the GA to CheckpointPath. The checkpoint
+// is first written to a temporary file so that an interruption can't corrupt
+// the previous checkpoint.
+func (gp *GP) saveCheckpoint(ga *eaopt.GA) error {
+	var cp = checkpoint{
+		Generations:  ga.Generations,
+		Age:          ga.Age,
+		RNG:          gp.rngSource.state(),
+		Populations:  make([]checkpointPopulation, len(ga.Populations)),
+		HallOfFame:   make([]checkpointIndividual, len(ga.HallOfFame)),
+		NEvaluations: gp.NEvaluations(),
+		Rows:         gp.rows,
+	}
+	if gp.bestVal != nil {
+		cp.BestValidation = &checkpointBestValidation{
+			Score:      jsonFloat(gp.bestVal.score),
+			Generation: gp.bestVal.generation,
+			Individual: newCheckpointIndividual(gp.bestVal.indi),
+		}
+	}
+	for i, pop := range ga.Populations {
+		cp.Populations[i] = checkpointPopulation{
+			ID:          pop.ID,
+			Age:         pop.Age,
+			Generations: pop.Generations,
+			RNG:         gp.popSources[i].state(),
+			Individuals: make([]checkpointIndividual, len(pop.Individuals)),
+		}
+		for j, indi := range pop.Individuals {
+			cp.Populations[i].Individuals[j] = newCheckpointIndividual(indi)
+		}
+	}
+	for i, indi := range ga.HallOfFame {
+		cp.HallOfFame[i] = newCheckpointIndividual(indi)
+	}
+	bytes, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	var tmp = gp.CheckpointPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, bytes, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, gp.CheckpointPath)
+}
+
+// loadCheckpoint restores the state of the GA from CheckpointPath. It returns
+// false if there is no checkpoint to load.
+func (gp *GP) loadCheckpoint(ga *eaopt.GA) (bool, error) {
+	bytes, err := ioutil.ReadFile(gp.CheckpointPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(bytes, &cp); err != nil {
+		return false, err
+	}
+	if len(cp.Populations) != len(ga.Populations) {
+		return false, fmt.Errorf("The checkpoint contains %d populations instead of %d",
+			len(cp.Populations), len(ga.Populations))
+	}
+	if len(cp.HallOfFame) != len(ga.HallOfFame) {
+		return false, fmt.Errorf("The checkpoint's hall of fame contains %d individuals instead of %d",
+			len(cp.HallOfFame), len(ga.HallOfFame))
+	}
+	// The individuals are evaluated on the rows of the checkpointed generation
+	for _, row := range cp.Rows {
+		if row < 0 || row >= len(gp.yFull) {
+			return false, fmt.Errorf("The checkpoint contains row %d but there are only %d rows",
+				row, len(gp.yFull))
+		}
+	}
+	gp.useRows(cp.Rows)
+	gp.prepareTrain()
+	for i, cpop := range cp.Populations {
+		if len(cpop.Individuals) != int(gp.NIndividuals) {
+			return false, fmt.Errorf("Population %d of the checkpoint contains %d individuals instead of %d",
+				i, len(cpop.Individuals), gp.NIndividuals)
+		}
+		var pop = &ga.Populations[i]
+		pop.ID = cpop.ID
+		pop.Age = cpop.Age
+		pop.Generations = cpop.Generations
+		pop.RNG, gp.popSources[i] = newTrackedRand(cpop.RNG)
+		for j, ci := range cpop.Individuals {
+			if pop.Individuals[j], err = gp.individual(ci); err != nil {
+				return false, err
+			}
+		}
+	}
+	for i, ci := range cp.HallOfFame {
+		if ga.HallOfFame[i], err = gp.individual(ci); err != nil {
+			return false, err
+		}
+	}
+	gp.bestVal = nil
+	if cbv := cp.BestValidation; cbv != nil {
+		indi, err := gp.individual(cbv.Individual)
+		if err != nil {
+			return false, err
+		}
+		gp.bestVal = &bestValidation{
+			score:      float64(cbv.Score),
+			generation: cbv.Generation,
+			indi:       indi,
+		}
+	}
+	ga.Generations = cp.Generations
+	ga.Age = cp.Age
+	gp.RNG, gp.rngSource = newTrackedRand(cp.RNG)
+	ga.RNG = gp.RNG
+	// The evaluations made while loading the checkpoint don't count
+	atomic.StoreUint64(gp.nEvaluations, cp.NEvaluations)
+	return true, nil
+}
@@ -1,26 +1,36 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"os"
+	"os/signal"
 	"strings"
 	"time"
 
 	"github.com/MaxHalford/xgp"
 	"github.com/MaxHalford/xgp/meta"
 	"github.com/MaxHalford/xgp/metrics"
+	"github.com/MaxHalford/xgp/op"
 	"github.com/gonum/floats"
 	"github.com/spf13/cobra"
 )
 
 type fitCmd struct {
-	flavor string
+	flavor     string
+	multiClass string
 
 	// GP learning parameters
 	lossName       string
 	evalName       string
 	parsimonyCoeff float64
 	polishBest     bool
+	polishMethod   string
+	linearScaling  bool
+	float32Eval    bool
+	normalize      bool
 	funcs          string
 	constMin       float64
 	constMax       float64
@@ -39,6 +49,22 @@ type fitCmd struct {
 	pPointMut     float64
 	pointMutRate  float64
 	pSubtreeCross float64
+	selection     string
+	tournSize     uint
+	sizePressure  float64
+	multiObj      bool
+	memeticRate   float64
+	memeticSteps  uint
+	memeticMode   string
+	maxDuration   time.Duration
+	maxEvals      uint64
+	cacheSize     uint
+	subtreeMB     uint
+	rowFrac       float64
+	rowFracGrowth float64
+	nEarlyStopGen uint
+	useBestVal    bool
+	seedExprs     string
 
 	// Ensemble learning parameters
 	nRounds              uint
@@ -55,15 +81,21 @@ type fitCmd struct {
 	verbose bool
 
 	// CLI parameters
-	ignoredCols string
-	outputPath  string
-	targetCol   string
-	valPath     string
+	paretoPath      string
+	statsPath       string
+	statsFormat     string
+	checkpointPath  string
+	checkpointEvery uint
+	resume          bool
+	ignoredCols     string
+	outputPath      string
+	targetCol       string
+	valPath         string
 
 	*cobra.Command
 }
 
-func (c *fitCmd) run(cmd *cobra.Command, args []string) error {
+func (c *fitCmd) run(cmd *cobra.Command, args []string) (err error) {
 	// Instantiate a random number generator
 	var rng *rand.Rand
 	if c.seed == 0 {
@@ -93,6 +125,10 @@ func (c *fitCmd) run(cmd *cobra.Command, args []string) error {
 		EvalMetric:     evalMetric,
 		ParsimonyCoeff: c.parsimonyCoeff,
 		PolishBest:     c.polishBest,
+		PolishMethod:   c.polishMethod,
+		LinearScaling:  c.linearScaling,
+		Float32:        c.float32Eval,
+		Normalize:      c.normalize,
 
 		Funcs:     c.funcs,
 		ConstMin:  c.constMin,
@@ -111,10 +147,69 @@ func (c *fitCmd) run(cmd *cobra.Command, args []string) error {
 		PPointMutation:    c.pPointMut,
 		PointMutationRate: c.pointMutRate,
 		PSubtreeCrossover: c.pSubtreeCross,
+		Selection:         c.selection,
+		TournamentSize:    c.tournSize,
+		SizePressure:      c.sizePressure,
+		MultiObjective:    c.multiObj,
+		MemeticRate:       c.memeticRate,
+		MemeticSteps:      c.memeticSteps,
+		MemeticMode:       c.memeticMode,
+		MaxDuration:       c.maxDuration,
+		MaxEvaluations:    c.maxEvals,
+		CacheSize:         c.cacheSize,
+		SubtreeCacheMB:    c.subtreeMB,
+		RowFraction:       c.rowFrac,
+		RowFractionGrowth: c.rowFracGrowth,
+
+		NEarlyStoppingGenerations: c.nEarlyStopGen,
+		UseBestValidation:         c.useBestVal,
+
+		CheckpointPath:  c.checkpointPath,
+		CheckpointEvery: c.checkpointEvery,
+		Resume:          c.resume,
 
 		RNG: rng,
 	}
 
+	// The Pareto front is obtained from a single GA
+	if c.paretoPath != "" && (c.flavor != "vanilla" || c.multiClass != "") {
+		return errors.New("The Pareto front can only be saved with the 'vanilla' flavor and without a multi-class strategy")
+	}
+
+	// Statistics are reported by a single GA
+	if c.statsPath != "" {
+		if c.flavor != "vanilla" || c.multiClass != "" {
+			return errors.New("Statistics can only be saved with the 'vanilla' flavor and without a multi-class strategy")
+		}
+		var sw *statsWriter
+		if sw, err = newStatsWriter(c.statsPath, c.statsFormat); err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := sw.close(); err == nil {
+				err = cerr
+			}
+		}()
+		config.Callback = sw.write
+	}
+
+	// Checkpoints contain the state of a single GA
+	if c.checkpointPath != "" && (c.flavor != "vanilla" || c.multiClass != "") {
+		return errors.New("Checkpoints can only be used with the 'vanilla' flavor and without a multi-class strategy")
+	}
+
+	// Parse the seeds
+	if c.seedExprs != "" {
+		if c.flavor != "vanilla" {
+			return errors.New("Seeds can only be used with the 'vanilla' flavor")
+		}
+		seeds, err := parseSeeds(c.seedExprs)
+		if err != nil {
+			return err
+		}
+		config.Seeds = seeds
+	}
+
 	// Load the training set in memory
 	train, duration, err := readFile(args[0])
 	if err != nil {
@@ -173,23 +268,20 @@ func (c *fitCmd) run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Train
+	// Determine how to instantiate an estimator
+	var newEstimator func(rng *rand.Rand) (meta.Estimator, error)
 	switch c.flavor {
 
 	case "vanilla":
-		gp, err := config.NewGP()
-		if err != nil {
-			return err
-		}
-		err = gp.Fit(XTrain, YTrain, nil, XVal, YVal, nil, c.verbose)
-		if err != nil {
-			return err
-		}
-		best, err := gp.BestProgram()
-		if err != nil {
-			return err
+		newEstimator = func(rng *rand.Rand) (meta.Estimator, error) {
+			var conf = config
+			conf.RNG = rng
+			gp, err := conf.NewGP()
+			if err != nil {
+				return nil, err
+			}
+			return gp, nil
 		}
-		return writeProgram(best, c.outputPath)
 
 	case "boosting":
 		loss, ok := lossMetric.(metrics.DiffMetric)
@@ -205,31 +297,148 @@ func (c *fitCmd) run(cmd *cobra.Command, args []string) error {
 				Tol: 1e-10,
 			}
 		}
-		gb, err := meta.NewGradientBoosting(
-			config,
-			c.nRounds,
-			c.nEarlyStoppingRounds,
-			c.learningRate,
-			ls,
-			loss,
-			c.rowSampling,
-			c.colSampling,
-			c.useBestRounds,
-			c.monitorEvery,
-			rng,
-		)
+		newEstimator = func(rng *rand.Rand) (meta.Estimator, error) {
+			var conf = config
+			conf.RNG = rng
+			gb, err := meta.NewGradientBoosting(
+				conf,
+				c.nRounds,
+				c.nEarlyStoppingRounds,
+				c.learningRate,
+				ls,
+				loss,
+				c.rowSampling,
+				c.colSampling,
+				c.useBestRounds,
+				c.monitorEvery,
+				rng,
+			)
+			if err != nil {
+				return nil, err
+			}
+			return gb, nil
+		}
+
+	default:
+		return errUnknownFlavor{c.flavor}
+	}
+
+	// Stop training on Ctrl-C so that the best model so far is still saved
+	ctx, stop := interruptContext()
+	defer stop()
+
+	// Train
+	switch c.multiClass {
+
+	case "":
+		est, err := newEstimator(rng)
+		if err != nil {
+			return err
+		}
+		err = est.(meta.ContextEstimator).FitContext(ctx, XTrain, YTrain, nil, XVal, YVal, nil, c.verbose)
+		if err != nil {
+			return err
+		}
+		switch est := est.(type) {
+		case *xgp.GP:
+			if c.paretoPath != "" {
+				if err := writeParetoFront(est, c.paretoPath); err != nil {
+					return err
+				}
+			}
+			// Multi-class classification produces a MultiProgram
+			switch best := est.GA.HallOfFame[0].Genome.(type) {
+			case *xgp.MultiProgram:
+				return writeMultiProgram(*best, c.outputPath)
+			case *xgp.Program:
+				return writeProgram(*best, c.outputPath)
+			}
+		case *meta.GradientBoosting:
+			return writeGradientBoosting(est, c.outputPath)
+		}
+		return errors.New("Unknown type of estimator")
+
+	case "ovr":
+		ovr, err := meta.NewOneVsRest(newEstimator, rng)
+		if err != nil {
+			return err
+		}
+		err = ovr.FitContext(ctx, XTrain, YTrain, nil, XVal, YVal, nil, c.verbose)
+		if err != nil {
+			return err
+		}
+		return writeOneVsRest(ovr, c.outputPath)
+
+	case "ovo":
+		ovo, err := meta.NewOneVsOne(newEstimator, rng)
 		if err != nil {
 			return err
 		}
-		err = gb.Fit(XTrain, YTrain, nil, XVal, YVal, nil, c.verbose)
+		err = ovo.FitContext(ctx, XTrain, YTrain, nil, XVal, YVal, nil, c.verbose)
 		if err != nil {
 			return err
 		}
-		return writeGradientBoosting(gb, c.outputPath)
+		return writeOneVsOne(ovo, c.outputPath)
 
 	}
 
-	return errUnknownFlavor{c.flavor}
+	return fmt.Errorf("Unknown multi-class strategy '%s', has to be one of ('ovr', 'ovo')", c.multiClass)
+}
+
+// interruptContext returns a context which is cancelled the first time the
+// process receives an interrupt signal. The following interrupt signals are
+// handled as usual, which means that the process can still be killed. The
+// returned function has to be called once the context isn't needed anymore.
+func interruptContext() (context.Context, func()) {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		signals     = make(chan os.Signal, 1)
+	)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			fmt.Println("\nInterrupted, stopping training and saving the best model so far")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+// parseSeeds parses a semicolon-separated list of seeds. Each seed is either a
+// mathematical expression or the path to a model.json file produced by the
+// fit command.
+func parseSeeds(exprs string) ([]op.Operator, error) {
+	var seeds = make([]op.Operator, 0)
+	for _, expr := range strings.Split(exprs, ";") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		if !strings.HasSuffix(expr, ".json") {
+			seed, err := op.ParseExpr(expr)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid seed '%s': %s", expr, err)
+			}
+			seeds = append(seeds, seed)
+			continue
+		}
+		sm, err := readModel(expr)
+		if err != nil {
+			return nil, err
+		}
+		switch model := sm.Model.(type) {
+		case xgp.Program:
+			seeds = append(seeds, model.Op)
+		case xgp.MultiProgram:
+			seeds = append(seeds, model.Ops...)
+		default:
+			return nil, fmt.Errorf("Models of flavor '%s' can't be used as seeds", sm.Flavor)
+		}
+	}
+	return seeds, nil
 }
 
 func newFitCmd() *fitCmd {
@@ -243,11 +452,16 @@ func newFitCmd() *fitCmd {
 	}
 
 	c.Flags().StringVarP(&c.flavor, "flavor", "", "boosting", "training flavor to use ('boosting' or 'vanilla')")
+	c.Flags().StringVarP(&c.multiClass, "multi_class", "", "", "wraps the training flavor for multi-class classification ('ovr' or 'ovo'); by default the flavor handles multiple classes itself")
 
 	c.Flags().StringVarP(&c.lossName, "loss", "", "mse", "metric used for scoring program; determines the task to perform")
 	c.Flags().StringVarP(&c.evalName, "eval", "", "", "metric used for monitoring progress; defaults to loss_metric if not provided")
 	c.Flags().Float64VarP(&c.parsimonyCoeff, "parsimony", "", 0.00001, "parsimony coefficient by which a program's height is multiplied to decrease it's fitness")
 	c.Flags().BoolVarP(&c.polishBest, "polish", "", true, "whether or not to polish the best program")
+	c.Flags().BoolVarP(&c.linearScaling, "linear_scaling", "", false, "fit an intercept and a slope to the outputs of each program, regression only")
+	c.Flags().BoolVarP(&c.float32Eval, "float32", "", false, "evaluate programs with single precision during the evolution")
+	c.Flags().BoolVarP(&c.normalize, "normalize", "", false, "algebraically normalize the programs produced by crossover and mutation")
+	c.Flags().StringVarP(&c.polishMethod, "polish_method", "", "cmaes", "method used for polishing the best program ('cmaes', 'lm' or 'bfgs')")
 	c.Flags().StringVarP(&c.funcs, "funcs", "", "min,max,add,sub,mul,div", "comma-separated set of authorised functions")
 	c.Flags().Float64VarP(&c.constMin, "const_min", "", -5, "lower bound used for generating random constants")
 	c.Flags().Float64VarP(&c.constMax, "const_max", "", 5, "upper bound used for generating random constants")
@@ -265,6 +479,22 @@ func newFitCmd() *fitCmd {
 	c.Flags().Float64VarP(&c.pPointMut, "p_point_mut", "", 0.1, "probability of applying point mutation")
 	c.Flags().Float64VarP(&c.pointMutRate, "point_mut_rate", "", 0.3, "probability of modifying an operator during point mutation")
 	c.Flags().Float64VarP(&c.pSubtreeCross, "p_sub_cross", "", 0.5, "probability of applying subtree crossover")
+	c.Flags().StringVarP(&c.selection, "selection", "", "tournament", "selection method ('tournament', 'lexicase', 'eps_lexicase' or 'double_tournament')")
+	c.Flags().UintVarP(&c.tournSize, "tournament_size", "", 3, "number of contestants in each tournament")
+	c.Flags().Float64VarP(&c.sizePressure, "size_pressure", "", 1.4, "probability times 2 of selecting the smallest of two programs during double tournaments, between 1 and 2")
+	c.Flags().BoolVarP(&c.multiObj, "multi_objective", "", false, "whether to minimize the loss and the number of operators together with NSGA-II or not")
+	c.Flags().Float64VarP(&c.memeticRate, "memetic_rate", "", 0, "proportion of offsprings whose constants are optimized at each generation")
+	c.Flags().UintVarP(&c.memeticSteps, "memetic_steps", "", 5, "number of iterations of the polishing method used for optimizing the constants of an offspring")
+	c.Flags().StringVarP(&c.memeticMode, "memetic_mode", "", "lamarckian", "whether to keep the optimized constants ('lamarckian') or only the improved fitness ('baldwinian')")
+	c.Flags().DurationVarP(&c.maxDuration, "max_duration", "", 0, "maximum training duration, for example '10m'; 0 means no limit")
+	c.Flags().Uint64VarP(&c.maxEvals, "max_evals", "", 0, "maximum number of program evaluations; 0 means no limit")
+	c.Flags().UintVarP(&c.subtreeMB, "subtree_cache_mb", "", 0, "megabytes used for memoizing the outputs of subtrees shared by programs; 0 disables the memoization")
+	c.Flags().UintVarP(&c.cacheSize, "cache_size", "", 0, "number of program fitnesses kept in a cache; 0 disables the cache")
+	c.Flags().Float64VarP(&c.rowFrac, "row_frac", "", 1, "fraction of the training rows randomly sampled for evaluating each generation")
+	c.Flags().Float64VarP(&c.rowFracGrowth, "row_frac_growth", "", 1, "factor by which the fraction of sampled rows is multiplied after each generation")
+	c.Flags().UintVarP(&c.nEarlyStopGen, "early_stopping_gens", "", 0, "number of generations after which the GA stops if the validation score doesn't improve; 0 means no early stopping")
+	c.Flags().BoolVarP(&c.useBestVal, "use_best_val", "", false, "whether to keep the program with the best validation score instead of the best training score or not")
+	c.Flags().StringVarP(&c.seedExprs, "seed_exprs", "", "", "semicolon-separated expressions or model.json paths used to seed the initial population ('vanilla' flavor only)")
 
 	c.Flags().UintVarP(&c.nRounds, "rounds", "", 50, "number of programs to use in case of using an ensemble")
 	c.Flags().UintVarP(&c.nEarlyStoppingRounds, "early_stopping", "", 5, "number of rounds after which training stops if the evaluation score worsens")
@@ -277,6 +507,12 @@ func newFitCmd() *fitCmd {
 
 	c.Flags().Int64VarP(&c.seed, "seed", "", 0, "seed for random number generation")
 
+	c.Flags().StringVarP(&c.statsPath, "stats", "", "", "path where to save the statistics of each generation ('vanilla' flavor only)")
+	c.Flags().StringVarP(&c.statsFormat, "stats_format", "", "csv", "format of the statistics file ('csv' or 'jsonl')")
+	c.Flags().StringVarP(&c.paretoPath, "pareto", "", "", "path where to save the Pareto front of loss versus complexity ('vanilla' flavor only)")
+	c.Flags().StringVarP(&c.checkpointPath, "checkpoint", "", "", "path where to periodically save the state of the GA ('vanilla' flavor only)")
+	c.Flags().UintVarP(&c.checkpointEvery, "checkpoint_every", "", 1, "number of generations between two checkpoints")
+	c.Flags().BoolVarP(&c.resume, "resume", "", false, "whether to resume from the checkpoint or not")
 	c.Flags().StringVarP(&c.ignoredCols, "ignore", "", "", "comma-separated columns to ignore")
 	c.Flags().StringVarP(&c.outputPath, "output", "", "model.json", "path where to save the JSON representation of the final model")
 	c.Flags().StringVarP(&c.targetCol, "target", "", "y", "name of the target column in the training and validation datasets")
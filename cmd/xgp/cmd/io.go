@@ -5,8 +5,12 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -52,6 +56,88 @@ func readCSV(path string) (dataframe.DataFrame, error) {
 	return df, nil
 }
 
+// A batchReader reads a CSV file a few rows at a time so that the file
+// doesn't have to fit in memory.
+type batchReader struct {
+	file   *os.File
+	csv    *csv.Reader
+	header []string
+	size   int
+}
+
+func newBatchReader(path string, size int) (*batchReader, error) {
+	if !strings.HasSuffix(path, ".csv") {
+		return nil, errors.New("Unknown file extension")
+	}
+	if size < 1 {
+		return nil, errors.New("The batch size has to be strictly positive")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var br = &batchReader{file: f, csv: csv.NewReader(bufio.NewReader(f)), size: size}
+	if br.header, err = br.csv.Read(); err != nil {
+		f.Close()
+		if err == io.EOF {
+			return nil, errors.New("The file is empty")
+		}
+		return nil, err
+	}
+	return br, nil
+}
+
+// col returns the index of a column.
+func (br batchReader) col(name string) (int, error) {
+	for i, col := range br.header {
+		if col == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("No column named %s", name)
+}
+
+// next returns the next rows of the file. It returns io.EOF once all the rows
+// have been read.
+func (br *batchReader) next() ([][]string, error) {
+	var rows = make([][]string, 0, br.size)
+	for len(rows) < br.size {
+		row, err := br.csv.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, io.EOF
+	}
+	return rows, nil
+}
+
+func (br *batchReader) close() error {
+	return br.file.Close()
+}
+
+// parseCols parses some of the columns of a batch of rows into a slice of
+// float64 slices, one per column. Values which can't be parsed are NaNs.
+func parseCols(rows [][]string, cols []int) [][]float64 {
+	var X = make([][]float64, len(cols))
+	for j, col := range cols {
+		X[j] = make([]float64, len(rows))
+		for i, row := range rows {
+			x, err := strconv.ParseFloat(row[col], 64)
+			if err != nil {
+				x = math.NaN()
+			}
+			X[j][i] = x
+		}
+	}
+	return X
+}
+
 func writeProgram(prog xgp.Program, path string) error {
 	bytes, err := json.Marshal(serialModel{
 		Flavor: "vanilla",
@@ -64,6 +150,58 @@ func writeProgram(prog xgp.Program, path string) error {
 	return err
 }
 
+func writeMultiProgram(mp xgp.MultiProgram, path string) error {
+	bytes, err := json.Marshal(serialModel{
+		Flavor: "softmax",
+		Model:  mp,
+	})
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path, bytes, perm)
+	return err
+}
+
+// paretoMember is a member of the Pareto front. Apart from the objectives it
+// has the same fields as a serialModel, hence it can be used as a model file.
+type paretoMember struct {
+	Loss       float64 `json:"loss"`
+	Complexity float64 `json:"complexity"`
+	serialModel
+}
+
+// writeParetoFront writes the Pareto front of a GP to a JSON file.
+func writeParetoFront(gp *xgp.GP, path string) error {
+	var members = make([]paretoMember, 0)
+	if progs, points, err := gp.ParetoFront(); err == nil {
+		for i, prog := range progs {
+			members = append(members, paretoMember{
+				Loss:        points[i].Loss,
+				Complexity:  points[i].Complexity,
+				serialModel: serialModel{Flavor: "vanilla", Model: prog},
+			})
+		}
+	} else {
+		mps, points, err := gp.MultiParetoFront()
+		if err != nil {
+			return err
+		}
+		for i, mp := range mps {
+			members = append(members, paretoMember{
+				Loss:        points[i].Loss,
+				Complexity:  points[i].Complexity,
+				serialModel: serialModel{Flavor: "softmax", Model: mp},
+			})
+		}
+	}
+	bytes, err := json.Marshal(members)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path, bytes, perm)
+	return err
+}
+
 func writeGradientBoosting(gb *meta.GradientBoosting, path string) error {
 	bytes, err := json.Marshal(serialModel{
 		Flavor: "boosting",
@@ -76,6 +214,30 @@ func writeGradientBoosting(gb *meta.GradientBoosting, path string) error {
 	return err
 }
 
+func writeOneVsRest(ovr *meta.OneVsRest, path string) error {
+	bytes, err := json.Marshal(serialModel{
+		Flavor: "ovr",
+		Model:  ovr,
+	})
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path, bytes, perm)
+	return err
+}
+
+func writeOneVsOne(ovo *meta.OneVsOne, path string) error {
+	bytes, err := json.Marshal(serialModel{
+		Flavor: "ovo",
+		Model:  ovo,
+	})
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path, bytes, perm)
+	return err
+}
+
 func readModel(path string) (sm serialModel, err error) {
 	// Read the model file
 	bytes, err := ioutil.ReadFile(path)
@@ -102,6 +264,14 @@ func readModel(path string) (sm serialModel, err error) {
 		}
 		sm.Model = prog
 		return
+	case "softmax":
+		var mp xgp.MultiProgram
+		err = json.Unmarshal(*raw["model"], &mp)
+		if err != nil {
+			return
+		}
+		sm.Model = mp
+		return
 	case "boosting":
 		var gb meta.GradientBoosting
 		err = json.Unmarshal(*raw["model"], &gb)
@@ -110,7 +280,142 @@ func readModel(path string) (sm serialModel, err error) {
 		}
 		sm.Model = gb
 		return
+	case "ovr":
+		var ovr meta.OneVsRest
+		err = json.Unmarshal(*raw["model"], &ovr)
+		if err != nil {
+			return
+		}
+		sm.Model = ovr
+		return
+	case "ovo":
+		var ovo meta.OneVsOne
+		err = json.Unmarshal(*raw["model"], &ovo)
+		if err != nil {
+			return
+		}
+		sm.Model = ovo
+		return
 	}
 	err = errUnknownFlavor{sm.Flavor}
 	return
 }
+
+// serialStats is the serializable form of xgp.GenerationStats. Non-finite
+// values are missing because they can't be represented in JSON.
+type serialStats struct {
+	Generation    uint     `json:"generation"`
+	Duration      float64  `json:"duration"`
+	NEvaluations  uint64   `json:"n_evaluations"`
+	BestFitness   *float64 `json:"best_fitness"`
+	MedianFitness *float64 `json:"median_fitness"`
+	WorstFitness  *float64 `json:"worst_fitness"`
+	MeanSize      float64  `json:"mean_size"`
+	MeanHeight    float64  `json:"mean_height"`
+	NDistinct     int      `json:"n_distinct"`
+	TrainScore    *float64 `json:"train_score"`
+	ValScore      *float64 `json:"val_score"`
+}
+
+var statsColumns = []string{
+	"generation", "duration", "n_evaluations", "best_fitness", "median_fitness", "worst_fitness",
+	"mean_size", "mean_height", "n_distinct", "train_score", "val_score",
+}
+
+func finite(x float64) *float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return nil
+	}
+	return &x
+}
+
+func newSerialStats(stats xgp.GenerationStats) serialStats {
+	return serialStats{
+		Generation:    stats.Generation,
+		Duration:      stats.Duration.Seconds(),
+		NEvaluations:  stats.NEvaluations,
+		BestFitness:   finite(stats.BestFitness),
+		MedianFitness: finite(stats.MedianFitness),
+		WorstFitness:  finite(stats.WorstFitness),
+		MeanSize:      stats.MeanSize,
+		MeanHeight:    stats.MeanHeight,
+		NDistinct:     stats.NDistinct,
+		TrainScore:    finite(stats.TrainScore),
+		ValScore:      finite(stats.ValScore),
+	}
+}
+
+func (ss serialStats) record() []string {
+	var formatPtr = func(x *float64) string {
+		if x == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*x, 'g', -1, 64)
+	}
+	return []string{
+		strconv.Itoa(int(ss.Generation)),
+		strconv.FormatFloat(ss.Duration, 'g', -1, 64),
+		strconv.FormatUint(ss.NEvaluations, 10),
+		formatPtr(ss.BestFitness),
+		formatPtr(ss.MedianFitness),
+		formatPtr(ss.WorstFitness),
+		strconv.FormatFloat(ss.MeanSize, 'g', -1, 64),
+		strconv.FormatFloat(ss.MeanHeight, 'g', -1, 64),
+		strconv.Itoa(ss.NDistinct),
+		formatPtr(ss.TrainScore),
+		formatPtr(ss.ValScore),
+	}
+}
+
+// A statsWriter writes the statistics of each generation to a file, either
+// in CSV format or as JSON lines. Errors are kept until the file is closed
+// because the GP's callback can't return any.
+type statsWriter struct {
+	file *os.File
+	csv  *csv.Writer
+	enc  *json.Encoder
+	err  error
+}
+
+func newStatsWriter(path, format string) (*statsWriter, error) {
+	if format != "csv" && format != "jsonl" {
+		return nil, fmt.Errorf("Unknown statistics format '%s', has to be 'csv' or 'jsonl'", format)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	var sw = &statsWriter{file: f}
+	if format == "csv" {
+		sw.csv = csv.NewWriter(f)
+		sw.err = sw.csv.Write(statsColumns)
+	} else {
+		sw.enc = json.NewEncoder(f)
+	}
+	return sw, nil
+}
+
+func (sw *statsWriter) write(stats xgp.GenerationStats) {
+	if sw.err != nil {
+		return
+	}
+	var ss = newSerialStats(stats)
+	if sw.csv != nil {
+		sw.err = sw.csv.Write(ss.record())
+		return
+	}
+	sw.err = sw.enc.Encode(ss)
+}
+
+func (sw *statsWriter) close() error {
+	if sw.csv != nil {
+		sw.csv.Flush()
+		if sw.err == nil {
+			sw.err = sw.csv.Error()
+		}
+	}
+	if err := sw.file.Close(); sw.err == nil {
+		sw.err = err
+	}
+	return sw.err
+}
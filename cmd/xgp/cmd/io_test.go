@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTempFile writes content to a file named name in a new temporary
+// directory, which has to be removed by the caller.
+func writeTempFile(name, content string) (string, string, error) {
+	dir, err := ioutil.TempDir("", "xgp")
+	if err != nil {
+		return "", "", err
+	}
+	var path = filepath.Join(dir, name)
+	if err = ioutil.WriteFile(path, []byte(content), perm); err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+	return dir, path, nil
+}
+
+func TestBatchReader(t *testing.T) {
+	var testCases = []struct {
+		content     string
+		size        int
+		batches     []int
+		raisesError bool
+	}{
+		{content: "a,b\n1,2\n3,4\n5,6\n", size: 2, batches: []int{2, 1}, raisesError: false},
+		{content: "a,b\n1,2\n3,4\n", size: 2, batches: []int{2}, raisesError: false},
+		{content: "a,b\n1,2\n3,4\n", size: 10, batches: []int{2}, raisesError: false},
+		{content: "a,b\n", size: 2, batches: []int{}, raisesError: false},
+		{content: "", size: 2, batches: nil, raisesError: true},
+		{content: "a,b\n1,2\n", size: 0, batches: nil, raisesError: true},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			dir, path, err := writeTempFile("data.csv", tc.content)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			defer os.RemoveAll(dir)
+			br, err := newBatchReader(path, tc.size)
+			if (err != nil) != tc.raisesError {
+				t.Errorf("Expected error %t, got %v", tc.raisesError, err)
+			}
+			if err != nil {
+				return
+			}
+			defer br.close()
+			var batches = make([]int, 0)
+			for {
+				rows, err := br.next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Errorf("Expected nil, got %s", err)
+					return
+				}
+				batches = append(batches, len(rows))
+			}
+			if fmt.Sprint(batches) != fmt.Sprint(tc.batches) {
+				t.Errorf("Expected batches of %v rows, got %v", tc.batches, batches)
+			}
+		})
+	}
+}
+
+func TestParseCols(t *testing.T) {
+	var X = parseCols([][]string{{"1", "a", "3"}, {"4", "5", "6"}}, []int{2, 1})
+	if len(X) != 2 {
+		t.Errorf("Expected 2 columns, got %d", len(X))
+		return
+	}
+	if X[0][0] != 3 || X[0][1] != 6 || X[1][1] != 5 {
+		t.Errorf("Unexpected columns %v", X)
+	}
+	// Values which can't be parsed are missing
+	if !math.IsNaN(X[1][0]) {
+		t.Errorf("Expected NaN, got %f", X[1][0])
+	}
+}
@@ -1,9 +1,38 @@
 package cmd
 
+import (
+	"github.com/MaxHalford/xgp"
+	"github.com/MaxHalford/xgp/meta"
+)
+
 type model interface {
 	Predict(X [][]float64, proba bool) ([]float64, error)
 }
 
+// predictProba returns one slice of probabilities per class for models that
+// handle multi-class classification, along with the classes in the same
+// order. ok is false for the other models, which output a single probability
+// through their Predict method.
+func predictProba(m model, X [][]float64) (probas [][]float64, classes []float64, ok bool, err error) {
+	switch m := m.(type) {
+	case xgp.MultiProgram:
+		probas, err = m.PredictProba(X)
+		return probas, m.Classes, true, err
+	case meta.GradientBoosting:
+		if len(m.Classes) > 2 {
+			probas, err = m.PredictProba(X)
+			return probas, m.Classes, true, err
+		}
+	case meta.OneVsRest:
+		probas, err = m.PredictProba(X)
+		return probas, m.Classes, true, err
+	case meta.OneVsOne:
+		probas, err = m.PredictProba(X)
+		return probas, m.Classes, true, err
+	}
+	return nil, nil, false, nil
+}
+
 type serialModel struct {
 	Flavor string `json:"flavor"`
 	Model  model  `json:"model"`
@@ -1,13 +1,15 @@
 package cmd
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gonum/floats"
-	"github.com/kniren/gota/dataframe"
-	"github.com/kniren/gota/series"
 	"github.com/spf13/cobra"
 )
 
@@ -17,61 +19,151 @@ type predictCmd struct {
 	proba      bool
 	outputPath string
 	keepCols   string
+	batchSize  uint
 
 	*cobra.Command
 }
 
-func (c *predictCmd) run(cmd *cobra.Command, args []string) error {
+func (c *predictCmd) run(cmd *cobra.Command, args []string) (err error) {
 	// Load the model
 	sm, err := readModel(c.modelPath)
 	if err != nil {
 		return err
 	}
 
-	// Load the dataset in memory
-	df, duration, err := readFile(args[0])
+	// Open the dataset, which is read in batches
+	br, err := newBatchReader(args[0], int(c.batchSize))
 	if err != nil {
 		return err
 	}
-	fmt.Println(fmt.Sprintf("Dataset took %v to load", duration))
+	defer br.close()
 
 	// Juggle with the column names
 	var (
-		featureCols = df.Names()
-		keptCols    = strings.Split(c.keepCols, ",")
+		featureCols = br.header
+		keptCols    []string
+		features    []int
+		kept        []int
 	)
+	if len(c.keepCols) > 0 {
+		keptCols = strings.Split(c.keepCols, ",")
+	}
 	for _, col := range keptCols {
 		featureCols = removeString(featureCols, col)
-	}
-
-	// Check the dataset doesn't contain any missing values
-	var XTest = dataFrameToFloat64(df.Select(featureCols))
-	for i, x := range XTest {
-		if floats.HasNaN(x) {
-			return fmt.Errorf("Column '%s' in the test set has missing values", featureCols[i])
+		idx, err := br.col(col)
+		if err != nil {
+			return err
 		}
+		kept = append(kept, idx)
 	}
-
-	// Make predictions
-	yPred, err := sm.Model.Predict(XTest, c.proba)
-	if err != nil {
-		return err
+	for _, col := range featureCols {
+		idx, _ := br.col(col)
+		features = append(features, idx)
 	}
 
-	// Save the predictions
+	// Create the output file, the header is written along with the first
+	// batch because the number of columns depends on the model
 	outFile, err := os.Create(c.outputPath)
 	if err != nil {
 		return err
 	}
-	if len(c.keepCols) > 0 {
-		df.Select(keptCols).Mutate(series.New(yPred, series.Float, c.targetCol)).WriteCSV(outFile)
-	} else {
-		dataframe.New(series.New(yPred, series.Float, c.targetCol)).WriteCSV(outFile)
+	defer func() {
+		if cerr := outFile.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	var (
+		w      = csv.NewWriter(outFile)
+		start  = time.Now()
+		nRows  int
+		header []string
+	)
+	for {
+		rows, err := br.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// Check the batch doesn't contain any missing values
+		var XTest = parseCols(rows, features)
+		for i, x := range XTest {
+			if floats.HasNaN(x) {
+				return fmt.Errorf("Column '%s' in the test set has missing values", featureCols[i])
+			}
+		}
+
+		// Make predictions; multi-class models output one column per class
+		// when probabilities are requested
+		var (
+			preds   [][]float64
+			classes []float64
+			ok      bool
+		)
+		if c.proba {
+			preds, classes, ok, err = predictProba(sm.Model, XTest)
+			if err != nil {
+				return err
+			}
+		}
+		if !ok {
+			yPred, err := sm.Model.Predict(XTest, c.proba)
+			if err != nil {
+				return err
+			}
+			preds = [][]float64{yPred}
+		}
+
+		// Write the predictions
+		if header == nil {
+			header = c.header(keptCols, classes)
+			if err := w.Write(header); err != nil {
+				return err
+			}
+		}
+		for i, row := range rows {
+			var record = make([]string, 0, len(header))
+			for _, idx := range kept {
+				record = append(record, row[idx])
+			}
+			for _, pred := range preds {
+				record = append(record, strconv.FormatFloat(pred[i], 'f', 6, 64))
+			}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		nRows += len(rows)
+	}
+	if header == nil {
+		if err := w.Write(c.header(keptCols, nil)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
 	}
+	fmt.Println(fmt.Sprintf("Made predictions for %d rows in %v", nRows, time.Since(start)))
 
 	return nil
 }
 
+// header returns the columns of the CSV output. If classes isn't nil then
+// there is one column per class, named after the target column and the class.
+func (c predictCmd) header(keptCols []string, classes []float64) []string {
+	var header = append([]string{}, keptCols...)
+	if classes == nil {
+		return append(header, c.targetCol)
+	}
+	for _, class := range classes {
+		header = append(header, fmt.Sprintf("%s_%s", c.targetCol, strconv.FormatFloat(class, 'f', -1, 64)))
+	}
+	return header
+}
+
 func newPredictCmd() *predictCmd {
 	c := &predictCmd{}
 	c.Command = &cobra.Command{
@@ -84,9 +176,10 @@ func newPredictCmd() *predictCmd {
 
 	c.Flags().StringVarP(&c.modelPath, "model", "", "model.json", "path to the model used to make predictions")
 	c.Flags().StringVarP(&c.targetCol, "target", "", "y", "name of the target column in the CSV output")
-	c.Flags().BoolVarP(&c.proba, "proba", "", false, "predict probabilities in case of classification")
+	c.Flags().BoolVarP(&c.proba, "proba", "", false, "predict probabilities in case of classification; multi-class models output one column per class, named after the target column and the class")
 	c.Flags().StringVarP(&c.outputPath, "output", "", "y_pred.csv", "path to the CSV output")
 	c.Flags().StringVarP(&c.keepCols, "keep", "", "", "comma-separated columns to keep in the CSV output")
+	c.Flags().UintVarP(&c.batchSize, "batch_size", "", 10000, "number of rows read and predicted at once")
 
 	return c
 }
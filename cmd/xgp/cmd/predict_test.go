@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	varModel = `{"flavor":"vanilla","model":{"op":{"type":"var","value":"0","operands":[]},"loss_metric":"mse"}}`
+	varOp    = `{"type":"var","value":"0","operands":[]}`
+)
+
+func TestPredictCmd(t *testing.T) {
+	var (
+		softmaxModel = fmt.Sprintf(
+			`{"flavor":"softmax","model":{"ops":[%s,%s,%s],"classes":[1,2.5,4],"loss_metric":"logloss"}}`,
+			varOp, varOp, varOp,
+		)
+		testCases = []struct {
+			model     string
+			keepCols  string
+			proba     bool
+			batchSize uint
+			output    string
+		}{
+			{
+				model:     varModel,
+				keepCols:  "id",
+				proba:     false,
+				batchSize: 2,
+				output:    "id,y\n7,1.000000\n8,2.000000\n9,3.000000\n",
+			},
+			{
+				model:     varModel,
+				keepCols:  "",
+				proba:     false,
+				batchSize: 10,
+				output:    "y\n7.000000\n8.000000\n9.000000\n",
+			},
+			{
+				model:     softmaxModel,
+				keepCols:  "id",
+				proba:     true,
+				batchSize: 2,
+				output:    "id,y_1,y_2.5,y_4\n7,0.333333,0.333333,0.333333\n8,0.333333,0.333333,0.333333\n9,0.333333,0.333333,0.333333\n",
+			},
+		}
+	)
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			dir, path, err := writeTempFile("test.csv", "id,a\n7,1\n8,2\n9,3\n")
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			defer os.RemoveAll(dir)
+			var c = newPredictCmd()
+			c.modelPath = filepath.Join(dir, "model.json")
+			c.outputPath = filepath.Join(dir, "y_pred.csv")
+			c.keepCols = tc.keepCols
+			c.proba = tc.proba
+			c.batchSize = tc.batchSize
+			if err = ioutil.WriteFile(c.modelPath, []byte(tc.model), perm); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if err = c.run(c.Command, []string{path}); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			output, err := ioutil.ReadFile(c.outputPath)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if string(output) != tc.output {
+				t.Errorf("Expected %q, got %q", tc.output, string(output))
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"math"
 
 	"github.com/MaxHalford/xgp/metrics"
+	"github.com/gonum/floats"
 	"github.com/spf13/cobra"
 )
 
@@ -11,6 +15,7 @@ type scoreCmd struct {
 	modelPath  string
 	targetCol  string
 	metricName string
+	batchSize  uint
 
 	*cobra.Command
 }
@@ -28,27 +33,121 @@ func (c *scoreCmd) run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Load the dataset in memory
-	df, duration, err := readFile(args[0])
+	// Open the dataset, which is read in batches
+	br, err := newBatchReader(args[0], int(c.batchSize))
 	if err != nil {
 		return err
 	}
-	fmt.Println(fmt.Sprintf("Dataset took %v to load", duration))
+	defer br.close()
 
-	// Check the target column exists
-	var columns = df.Names()
-	if !containsString(columns, c.targetCol) {
-		return fmt.Errorf("No column named %s", c.targetCol)
-	}
-
-	// Make predictions
-	yPred, err := sm.Model.Predict(dataFrameToFloat64(df), metric.NeedsProbabilities())
+	// Check the target column exists, the other columns are the features
+	target, err := br.col(c.targetCol)
 	if err != nil {
 		return err
 	}
+	var (
+		featureCols = removeString(br.header, c.targetCol)
+		features    []int
+	)
+	for _, col := range featureCols {
+		idx, _ := br.col(col)
+		features = append(features, idx)
+	}
+
+	// Metrics which are averages over the rows are computed batch by batch,
+	// in which case the memory usage doesn't depend on the size of the
+	// dataset. The other metrics, such as ROC AUC, need the target and the
+	// predictions of every row.
+	var (
+		mean, finish, decomposable = batchMean(metric)
+		sum                        float64
+		n                          int
+		YTest, yPred               []float64
+		probas                     [][]float64
+		classes                    []float64
+	)
+	for {
+		rows, err := br.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// Check the batch doesn't contain any missing values
+		var X = parseCols(rows, features)
+		for i, x := range X {
+			if floats.HasNaN(x) {
+				return fmt.Errorf("Column '%s' in the test set has missing values", featureCols[i])
+			}
+		}
+		var y = parseCols(rows, []int{target})[0]
+
+		// Make predictions, multi-class models output one slice of
+		// probabilities per class if the metric needs probabilities
+		var (
+			p  [][]float64
+			ok bool
+		)
+		if metric.NeedsProbabilities() {
+			p, classes, ok, err = predictProba(sm.Model, X)
+			if err != nil {
+				return err
+			}
+		}
+		if ok {
+			if _, isProba := metric.(metrics.ProbaMetric); !isProba {
+				return fmt.Errorf("The '%s' metric can't be used for multi-class classification", metric.String())
+			}
+			if decomposable {
+				score, err := mean.(metrics.ProbaMetric).ApplyProba(y, p, classes, nil)
+				if err != nil {
+					return err
+				}
+				sum += score * float64(len(y))
+				n += len(y)
+				continue
+			}
+			if probas == nil {
+				probas = make([][]float64, len(p))
+			}
+			for k := range p {
+				probas[k] = append(probas[k], p[k]...)
+			}
+			YTest = append(YTest, y...)
+			continue
+		}
+		preds, err := sm.Model.Predict(X, metric.NeedsProbabilities())
+		if err != nil {
+			return err
+		}
+		if decomposable {
+			score, err := mean.Apply(y, preds, nil)
+			if err != nil {
+				return err
+			}
+			sum += score * float64(len(y))
+			n += len(y)
+			continue
+		}
+		YTest = append(YTest, y...)
+		yPred = append(yPred, preds...)
+	}
 
 	// Calculate score
-	score, err := metric.Apply(df.Col(c.targetCol).Float(), yPred, nil)
+	var score float64
+	switch {
+	case decomposable:
+		if n == 0 {
+			return errors.New("The dataset has no rows")
+		}
+		score = finish(sum / float64(n))
+	case probas != nil:
+		score, err = metric.(metrics.ProbaMetric).ApplyProba(YTest, probas, classes, nil)
+	default:
+		score, err = metric.Apply(YTest, yPred, nil)
+	}
 	if err != nil {
 		return err
 	}
@@ -57,6 +156,26 @@ func (c *scoreCmd) run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// batchMean returns the Metric whose scores on each batch can be averaged,
+// weighted by the number of rows of each batch, along with the function that
+// turns the average into the score of the given Metric. decomposable is false
+// if the given Metric can't be computed in such a way.
+func batchMean(metric metrics.Metric) (mean metrics.Metric, finish func(float64) float64, decomposable bool) {
+	switch metric := metric.(type) {
+	case metrics.MSE, metrics.MAE, metrics.LogLoss, metrics.Accuracy:
+		return metric, func(x float64) float64 { return x }, true
+	case metrics.RMSE:
+		return metrics.MSE{}, math.Sqrt, true
+	case metrics.Negative:
+		mean, finishMetric, decomposable := batchMean(metric.Metric)
+		if !decomposable {
+			return nil, nil, false
+		}
+		return mean, func(x float64) float64 { return -finishMetric(x) }, true
+	}
+	return nil, nil, false
+}
+
 func newScoreCmd() *scoreCmd {
 	c := &scoreCmd{}
 	c.Command = &cobra.Command{
@@ -70,6 +189,7 @@ func newScoreCmd() *scoreCmd {
 	c.Flags().StringVarP(&c.modelPath, "model", "", "model.json", "path to the program to score")
 	c.Flags().StringVarP(&c.targetCol, "target", "", "y", "name of the target column in the dataset")
 	c.Flags().StringVarP(&c.metricName, "metric", "", "mse", "evaluation metric")
+	c.Flags().UintVarP(&c.batchSize, "batch_size", "", 10000, "number of rows read and predicted at once")
 
 	return c
 }
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MaxHalford/xgp/metrics"
+)
+
+func TestScoreCmd(t *testing.T) {
+	var testCases = []struct {
+		content     string
+		metricName  string
+		batchSize   uint
+		raisesError bool
+	}{
+		{content: "a,y\n1,1\n2,2\n3,4\n", metricName: "mse", batchSize: 2, raisesError: false},
+		{content: "a,y\n1,1\n2,2\n3,4\n", metricName: "r2", batchSize: 2, raisesError: false},
+		{content: "a,y\n1,1\n,2\n3,4\n", metricName: "mse", batchSize: 2, raisesError: true},
+		{content: "a,y\n", metricName: "mse", batchSize: 2, raisesError: true},
+		{content: "a,y\n1,1\n", metricName: "mse", batchSize: 0, raisesError: true},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			dir, path, err := writeTempFile("test.csv", tc.content)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			defer os.RemoveAll(dir)
+			var c = newScoreCmd()
+			c.modelPath = filepath.Join(dir, "model.json")
+			c.metricName = tc.metricName
+			c.batchSize = tc.batchSize
+			if err = ioutil.WriteFile(c.modelPath, []byte(varModel), perm); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			err = c.run(c.Command, []string{path})
+			if (err != nil) != tc.raisesError {
+				t.Errorf("Expected error %t, got %v", tc.raisesError, err)
+			}
+		})
+	}
+}
+
+func TestBatchMean(t *testing.T) {
+	var (
+		yTrue     = []float64{1, 2, 3, 4, 5}
+		yPred     = []float64{1, 3, 3, 2, 6}
+		testCases = []struct {
+			metric       metrics.Metric
+			decomposable bool
+		}{
+			{metric: metrics.MSE{}, decomposable: true},
+			{metric: metrics.RMSE{}, decomposable: true},
+			{metric: metrics.MAE{}, decomposable: true},
+			{metric: metrics.Negative{Metric: metrics.RMSE{}}, decomposable: true},
+			{metric: metrics.R2{}, decomposable: false},
+			{metric: metrics.Negative{Metric: metrics.R2{}}, decomposable: false},
+		}
+	)
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			mean, finish, decomposable := batchMean(tc.metric)
+			if decomposable != tc.decomposable {
+				t.Errorf("Expected %t, got %t", tc.decomposable, decomposable)
+				return
+			}
+			if !decomposable {
+				return
+			}
+			// Batches of 2 and 3 rows give the same score as the whole set
+			a, _ := mean.Apply(yTrue[:2], yPred[:2], nil)
+			b, _ := mean.Apply(yTrue[2:], yPred[2:], nil)
+			var (
+				score       = finish((2*a + 3*b) / 5)
+				expected, _ = tc.metric.Apply(yTrue, yPred, nil)
+			)
+			if math.Abs(score-expected) > 10e-10 {
+				t.Errorf("Expected %.5f, got %.5f", expected, score)
+			}
+		})
+	}
+}
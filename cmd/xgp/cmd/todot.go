@@ -12,7 +12,9 @@ import (
 
 type toDOTCmd struct {
 	modelPath  string
+	expr       string
 	round      uint
+	class      uint
 	shell      bool
 	save       bool
 	outputPath string // Only applies if save is true
@@ -21,6 +23,21 @@ type toDOTCmd struct {
 }
 
 func (c *toDOTCmd) run(cmd *cobra.Command, args []string) error {
+	var (
+		disp = op.GraphvizDisplay{}
+		str  string
+	)
+
+	// Parse the formula if one is provided
+	if c.expr != "" {
+		operator, err := op.ParseExpr(c.expr)
+		if err != nil {
+			return err
+		}
+		str = disp.Apply(operator)
+		return c.output(str)
+	}
+
 	// Load the model
 	sm, err := readModel(c.modelPath)
 	if err != nil {
@@ -28,23 +45,59 @@ func (c *toDOTCmd) run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Build the Graphviz representation
-	var (
-		disp = op.GraphvizDisplay{}
-		str  string
-	)
 	switch sm.Flavor {
 	case "vanilla":
-		str = disp.Apply(sm.Model.(xgp.Program).Op)
+		str = disp.Apply(sm.Model.(xgp.Program).ScaledOp())
+	case "softmax":
+		mp := sm.Model.(xgp.MultiProgram)
+		if uint(len(mp.Ops)) < c.class+1 {
+			return fmt.Errorf("Model only contains %d classes", len(mp.Ops))
+		}
+		str = disp.Apply(mp.Ops[c.class])
 	case "boosting":
 		gb := sm.Model.(meta.GradientBoosting)
+		if len(gb.ClassPrograms) > 0 {
+			if uint(len(gb.ClassPrograms)) < c.round+1 {
+				return fmt.Errorf("Ensemble only contains %d rounds", len(gb.ClassPrograms))
+			}
+			if uint(len(gb.ClassPrograms[c.round])) < c.class+1 {
+				return fmt.Errorf("Model only contains %d classes", len(gb.ClassPrograms[c.round]))
+			}
+			str = disp.Apply(gb.ClassPrograms[c.round][c.class].ScaledOp())
+			break
+		}
 		if uint(len(gb.Programs)) < c.round+1 {
 			return fmt.Errorf("Ensemble only contains %d programs", len(gb.Programs))
 		}
-		str = disp.Apply(gb.Programs[c.round].Op)
+		str = disp.Apply(gb.Programs[c.round].ScaledOp())
+	case "ovr", "ovo":
+		var preds []meta.Predictor
+		if ovr, ok := sm.Model.(meta.OneVsRest); ok {
+			preds = ovr.Predictors
+		} else {
+			preds = sm.Model.(meta.OneVsOne).Predictors
+		}
+		if uint(len(preds)) < c.class+1 {
+			return fmt.Errorf("Model only contains %d sub-models", len(preds))
+		}
+		switch pred := preds[c.class].(type) {
+		case xgp.Program:
+			str = disp.Apply(pred.ScaledOp())
+		case *meta.GradientBoosting:
+			if uint(len(pred.Programs)) < c.round+1 {
+				return fmt.Errorf("Ensemble only contains %d programs", len(pred.Programs))
+			}
+			str = disp.Apply(pred.Programs[c.round].ScaledOp())
+		}
 	default:
 		return errUnknownFlavor{sm.Flavor}
 	}
 
+	return c.output(str)
+}
+
+// output displays and/or saves a Graphviz representation.
+func (c *toDOTCmd) output(str string) error {
 	// Output in the shell if instructed
 	if c.shell {
 		fmt.Println(str)
@@ -75,7 +128,9 @@ func newToDOTCmd() *toDOTCmd {
 	}
 
 	c.Flags().StringVarP(&c.modelPath, "model", "", "model.json", "path to the model used to make predictions")
+	c.Flags().StringVarP(&c.expr, "expr", "", "", "formula to represent instead of a model, for example 'x0*cos(x1)+3.2'")
 	c.Flags().UintVarP(&c.round, "round", "", 0, "position of the program in the ensemble")
+	c.Flags().UintVarP(&c.class, "class", "", 0, "position of the class (or of the pair of classes for one-vs-one) in case of multi-class classification")
 	c.Flags().BoolVarP(&c.shell, "shell", "", true, "output in the terminal or not")
 	c.Flags().BoolVarP(&c.save, "save", "", false, "save to a DOT file or not")
 	c.Flags().StringVarP(&c.outputPath, "output", "", "program.dot", "path to the DOT file output")
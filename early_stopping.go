@@ -0,0 +1,65 @@
+package xgp
+
+import (
+	"math"
+
+	"github.com/MaxHalford/eaopt"
+)
+
+// bestValidation stores the best Individual in terms of validation score along
+// with the generation where it was found.
+type bestValidation struct {
+	score      float64
+	generation uint
+	indi       eaopt.Individual
+}
+
+// trackValidation keeps track of the best Program found so far in terms of
+// validation score. It only does something if a validation set is provided and
+// if early stopping or UseBestValidation are enabled.
+func (gp *GP) trackValidation(ga *eaopt.GA) error {
+	if gp.NEarlyStoppingGenerations == 0 && !gp.UseBestValidation {
+		return nil
+	}
+	if gp.XVal == nil || gp.YVal == nil {
+		return nil
+	}
+	score, err := gp.score(gp.EvalMetric, gp.XVal, gp.YVal, gp.WVal)
+	if err != nil {
+		return err
+	}
+	if gp.EvalMetric.BiggerIsBetter() {
+		score = -score
+	}
+	if gp.bestVal == nil || score < gp.bestVal.score || math.IsNaN(gp.bestVal.score) {
+		// The Genome is cloned because the hall of fame's Genomes might be
+		// modified when it is updated
+		var best = ga.HallOfFame[0]
+		best.Genome = best.Genome.Clone()
+		gp.bestVal = &bestValidation{
+			score:      score,
+			generation: ga.Generations,
+			indi:       best,
+		}
+	}
+	return nil
+}
+
+// valPlateaued indicates if the validation score hasn't improved during the
+// last NEarlyStoppingGenerations generations.
+func (gp *GP) valPlateaued(ga *eaopt.GA) bool {
+	return gp.NEarlyStoppingGenerations > 0 && gp.bestVal != nil &&
+		ga.Generations-gp.bestVal.generation >= gp.NEarlyStoppingGenerations
+}
+
+// monitor is called once the initial populations have been evaluated and
+// then after each generation.
+func (gp *GP) monitor(ga *eaopt.GA) {
+	if gp.fitErr != nil {
+		return
+	}
+	if gp.fitErr = gp.trackValidation(ga); gp.fitErr != nil {
+		return
+	}
+	gp.reportStats(ga)
+}
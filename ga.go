@@ -1,19 +1,58 @@
 package xgp
 
 import (
+	"errors"
 	"math"
 	"math/rand"
 
 	"github.com/MaxHalford/eaopt"
+	"github.com/MaxHalford/xgp/metrics"
 	"github.com/MaxHalford/xgp/op"
 )
 
-// Evaluate is required to implement eaopt.Genome.
-func (prog Program) Evaluate() (float64, error) {
+// Evaluate is required to implement eaopt.Genome. The fitness is looked up
+// in the GP's cache beforehand if there is one.
+func (prog *Program) Evaluate() (float64, error) {
+	var cache = prog.GP.cache
+	if cache == nil {
+		return prog.evaluate()
+	}
+	var hash = op.Hash(prog.Op)
+	if entry, ok := cache.get(hash, prog.Op); ok {
+		prog.residuals = entry.residuals
+		prog.Scaling = entry.scaling
+		return entry.fitness, nil
+	}
+	fitness, err := prog.evaluate()
+	if err != nil {
+		return fitness, err
+	}
+	cache.add(cacheEntry{
+		hash:      hash,
+		ops:       []op.Operator{prog.Op},
+		fitness:   fitness,
+		residuals: prog.residuals,
+		scaling:   prog.Scaling,
+	})
+	return fitness, nil
+}
+
+// evaluate computes the fitness of a Program on the training set.
+func (prog *Program) evaluate() (float64, error) {
 	// For convenience
 	gp := prog.GP
-	// Run the training set through the Program
-	var yPred, err = prog.Predict(gp.X, gp.LossMetric.NeedsProbabilities())
+	gp.countEvaluation()
+	// Use single precision all the way through if the loss metric allows it
+	if metric, ok := float32Metric(gp.LossMetric); ok && gp.x32 != nil {
+		return prog.evaluate32(metric)
+	}
+	// Run the training set through the Program, the linear scaling is fitted
+	// beforehand if necessary
+	var yRaw = gp.evalTrain(prog.Op)
+	if gp.LinearScaling {
+		prog.Scaling = fitScaling(yRaw, gp.Y, gp.W)
+	}
+	var yPred, err = prog.transform(yRaw, gp.LossMetric.NeedsProbabilities())
 	if err != nil {
 		return math.Inf(1), err
 	}
@@ -22,56 +61,94 @@ func (prog Program) Evaluate() (float64, error) {
 	if err != nil {
 		return math.Inf(1), err
 	}
+	if prog.residuals, err = gp.residuals(yPred); err != nil {
+		return math.Inf(1), err
+	}
+	return prog.penalize(fitness), nil
+}
+
+// evaluate32 is the same as evaluate except that the fitness is computed with
+// single precision. The linear scaling is fitted on the single precision
+// outputs and the residuals are computed on the scaled outputs converted back
+// to double precision.
+func (prog *Program) evaluate32(metric metrics.Float32Metric) (float64, error) {
+	gp := prog.GP
+	var yPred = op.EvalFloat32(prog.Op, gp.x32)
+	for _, y := range yPred {
+		if y != y {
+			return math.Inf(1), errors.New("yPred contains NaNs")
+		}
+	}
+	if gp.LinearScaling {
+		prog.Scaling = fitScaling(float64s(yPred), gp.Y, gp.W)
+		var intercept, slope = float32(prog.Scaling.Intercept), float32(prog.Scaling.Slope)
+		for i, y := range yPred {
+			yPred[i] = intercept + slope*y
+		}
+	}
+	fitness, err := metric.Apply32(gp.y32, yPred, gp.w32)
+	if err != nil {
+		return math.Inf(1), err
+	}
+	if gp.residualMetric != nil {
+		if prog.residuals, err = gp.residuals(float64s(yPred)); err != nil {
+			return math.Inf(1), err
+		}
+	}
+	return prog.penalize(fitness), nil
+}
+
+// float32Metric returns a Metric as a Float32Metric if it can be applied with
+// single precision. A Negative can only be applied with single precision if
+// the Metric it wraps can.
+func float32Metric(metric metrics.Metric) (metrics.Float32Metric, bool) {
+	if neg, ok := metric.(metrics.Negative); ok {
+		if _, ok := float32Metric(neg.Metric); !ok {
+			return nil, false
+		}
+		return neg, true
+	}
+	var m, ok = metric.(metrics.Float32Metric)
+	return m, ok
+}
+
+// penalize replaces a NaN fitness with +Inf and applies the parsimony
+// coefficient.
+func (prog Program) penalize(fitness float64) float64 {
 	if math.IsNaN(fitness) {
-		return math.Inf(1), nil
+		return math.Inf(1)
 	}
-	// Apply the parsimony coefficient
-	if gp.ParsimonyCoeff != 0 {
-		fitness += gp.ParsimonyCoeff * float64(op.CountOps(prog.Op))
+	if prog.GP.ParsimonyCoeff != 0 && !prog.GP.MultiObjective {
+		fitness += prog.GP.ParsimonyCoeff * float64(op.CountOps(prog.Op))
 	}
-	return fitness, nil
+	return fitness
 }
 
 // Mutate is required to implement eaopt.Genome.
 func (prog *Program) Mutate(rng *rand.Rand) {
-	defer func() { prog.Op = prog.Op.Simplify() }()
-	var (
-		pHoist   = prog.GP.PHoistMutation
-		pSubtree = prog.GP.PSubtreeMutation
-		pPoint   = prog.GP.PPointMutation
-		dice     = rng.Float64() * (pHoist + pSubtree + pPoint)
-	)
-	// Apply hoist mutation
-	if dice < pHoist {
-		prog.Op = prog.GP.HoistMutation.Apply(prog.Op, rng)
-		return
-	}
-	// Apply subtree mutation
-	if dice < pHoist+pSubtree {
-		prog.Op = prog.GP.SubtreeMutation.Apply(prog.Op, rng)
-		return
-	}
-	// Apply point mutation
-	prog.Op = prog.GP.PointMutation.Apply(prog.Op, rng)
+	prog.Op = prog.GP.mutate(prog.Op, rng)
 }
 
 // Crossover is required to implement eaopt.Genome.
 func (prog *Program) Crossover(prog2 eaopt.Genome, rng *rand.Rand) {
 	newOp1, newOp2 := prog.GP.SubtreeCrossover.Apply(prog.Op, prog2.(*Program).Op, rng)
-	prog.Op = newOp1.Simplify()
-	prog2.(*Program).Op = newOp2.Simplify()
+	prog.Op = prog.GP.simplify(newOp1)
+	prog2.(*Program).Op = prog.GP.simplify(newOp2)
 }
 
 // Clone is required to implement eaopt.Genome.
 func (prog Program) Clone() eaopt.Genome {
 	return &Program{
-		Op: prog.Op,
-		GP: prog.GP,
+		Op:        prog.Op,
+		GP:        prog.GP,
+		Scaling:   prog.Scaling,
+		residuals: prog.residuals,
 	}
 }
 
 // Custom genetic algorithm model.
 type gaModel struct {
+	gp         *GP
 	selector   eaopt.Selector
 	pMutate    float64
 	pCrossover float64
@@ -79,10 +156,16 @@ type gaModel struct {
 
 // Apply is necessary to implement eaopt.Model.
 func (mod gaModel) Apply(pop *eaopt.Population) error {
+	// The residuals used by lexicase selection don't change during a
+	// generation, hence they are only gathered once
+	var selector = mod.selector
+	if sel, ok := selector.(selLexicase); ok {
+		selector = sel.withCases(pop.Individuals)
+	}
 	var offsprings = make(eaopt.Individuals, len(pop.Individuals))
 	for i := range offsprings {
 		// Select an individual
-		selected, _, err := mod.selector.Apply(1, pop.Individuals, pop.RNG)
+		selected, _, err := selector.Apply(1, pop.Individuals, pop.RNG)
 		if err != nil {
 			return err
 		}
@@ -94,7 +177,7 @@ func (mod gaModel) Apply(pop *eaopt.Population) error {
 			offspring.Mutate(pop.RNG)
 		} else if dice < (mod.pMutate + mod.pCrossover) {
 			// Crossover
-			selected, _, err := mod.selector.Apply(1, pop.Individuals, pop.RNG)
+			selected, _, err := selector.Apply(1, pop.Individuals, pop.RNG)
 			if err != nil {
 				return err
 			}
@@ -103,6 +186,12 @@ func (mod gaModel) Apply(pop *eaopt.Population) error {
 		// Insert the offsprings into the new population
 		offsprings[i] = offspring
 	}
+	// Tune the constants of some offsprings
+	if mod.gp != nil {
+		if err := mod.gp.memetic(offsprings, pop.RNG); err != nil {
+			return err
+		}
+	}
 	// Replace the population's individuals with the offsprings
 	copy(pop.Individuals, offsprings)
 	return nil
@@ -110,5 +199,8 @@ func (mod gaModel) Apply(pop *eaopt.Population) error {
 
 // Validate is necessary to implement eaopt.Model.
 func (mod gaModel) Validate() error {
+	if mod.selector != nil {
+		return mod.selector.Validate()
+	}
 	return nil
 }
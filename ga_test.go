@@ -96,3 +96,24 @@ func TestGAValidate(t *testing.T) {
 		t.Errorf("Expected nil, got %s", err)
 	}
 }
+
+func TestFloat32Metric(t *testing.T) {
+	var testCases = []struct {
+		metric metrics.Metric
+		ok     bool
+	}{
+		{metric: metrics.MSE{}, ok: true},
+		{metric: metrics.Negative{Metric: metrics.R2{}}, ok: true},
+		{metric: metrics.Negative{Metric: metrics.Negative{Metric: metrics.R2{}}}, ok: true},
+		{metric: metrics.LogLoss{}, ok: false},
+		{metric: metrics.Negative{Metric: metrics.Accuracy{}}, ok: false},
+		{metric: metrics.Negative{Metric: metrics.Negative{Metric: metrics.Accuracy{}}}, ok: false},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			if _, ok := float32Metric(tc.metric); ok != tc.ok {
+				t.Errorf("Expected %t, got %t", tc.ok, ok)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package xgp
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/MaxHalford/eaopt"
@@ -29,14 +34,102 @@ type GP struct {
 	HoistMutation    HoistMutation
 	SubtreeCrossover SubtreeCrossover
 
-	fm       map[uint][]op.Operator
-	X        [][]float64
-	Y        []float64
-	W        []float64
+	fm map[uint][]op.Operator
+	X  [][]float64
+	Y  []float64
+	W  []float64
+	// xFull, yFull and wFull are the full training set, whereas X, Y and W
+	// might only contain a subset of it's rows if RowFraction is below 1, in
+	// which case rows contains the indexes of the rows
+	xFull [][]float64
+	yFull []float64
+	wFull []float64
+	rows  []int
+	// x32, y32 and w32 are single precision copies of X, Y and W which are
+	// only set if Float32 is
+	x32      [][]float32
+	y32      []float32
+	w32      []float32
 	XVal     [][]float64
 	YVal     []float64
 	WVal     []float64
 	nClasses int
+	classes  []float64
+	ctx      context.Context
+	// nEvaluations is shared by the copies of the GP and is updated
+	// atomically
+	nEvaluations *uint64
+	started      bool
+	start        time.Time
+	bestVal      *bestValidation
+	fitErr       error
+	// cache stores the fitnesses of the Programs evaluated during the
+	// current call to Fit, it is nil if CacheSize is 0
+	cache *fitnessCache
+	// subtrees memoizes the outputs of the subtrees evaluated on the training
+	// set, it is nil if SubtreeCacheMB is 0
+	subtrees *op.SubtreeCache
+	// Random number generators which can be checkpointed
+	rngSource  *trackedSource
+	popSources []*trackedSource
+	// residualMetric is only set if the selection method needs the errors
+	// made on each sample
+	residualMetric metrics.ResidualMetric
+}
+
+// NEvaluations returns the number of times a Program has been evaluated
+// during the last call to Fit.
+func (gp GP) NEvaluations() uint64 {
+	if gp.nEvaluations == nil {
+		return 0
+	}
+	return atomic.LoadUint64(gp.nEvaluations)
+}
+
+// countEvaluation increments the number of evaluations.
+func (gp GP) countEvaluation() {
+	if gp.nEvaluations != nil {
+		atomic.AddUint64(gp.nEvaluations, 1)
+	}
+}
+
+// evalTrain evaluates an Operator on the training set. The Operator is
+// evaluated with single precision if Float32 is set, otherwise the subtree
+// cache is used if there is one.
+func (gp GP) evalTrain(operator op.Operator) []float64 {
+	if gp.x32 != nil {
+		return float64s(op.EvalFloat32(operator, gp.x32))
+	}
+	if gp.subtrees != nil {
+		return gp.subtrees.Eval(operator)
+	}
+	return evalOp(operator, gp.X)
+}
+
+// prepareTrain empties the caches and copies the training set with single
+// precision if necessary, which has to be done whenever the training set
+// changes. The subtree cache isn't used with single precision.
+func (gp *GP) prepareTrain() {
+	if gp.cache != nil {
+		gp.cache.reset()
+	}
+	gp.subtrees = nil
+	gp.x32, gp.y32, gp.w32 = nil, nil, nil
+	if gp.Float32 {
+		gp.x32 = make([][]float32, len(gp.X))
+		for j, x := range gp.X {
+			gp.x32[j] = float32s(x)
+		}
+		gp.y32 = float32s(gp.Y)
+		gp.w32 = float32s(gp.W)
+	} else if gp.SubtreeCacheMB > 0 {
+		gp.subtrees = op.NewSubtreeCache(gp.X, int(gp.SubtreeCacheMB)<<20)
+	}
+}
+
+// budgetExhausted indicates if MaxEvaluations has been reached.
+func (gp *GP) budgetExhausted() bool {
+	return gp.MaxEvaluations > 0 && gp.NEvaluations() >= gp.MaxEvaluations
 }
 
 // String representation of an GP.
@@ -49,57 +142,341 @@ func (gp GP) BestProgram() (Program, error) {
 	if len(gp.GA.HallOfFame) == 0 {
 		return Program{}, errors.New("The GP has not been trained yet")
 	}
-	return *gp.GA.HallOfFame[0].Genome.(*Program), nil
+	prog, ok := gp.GA.HallOfFame[0].Genome.(*Program)
+	if !ok {
+		return Program{}, errors.New("The GP has been trained on a multi-class classification task, use BestMultiProgram instead")
+	}
+	return *prog, nil
 }
 
-func (gp GP) progress(start time.Time) string {
-	// Add time spent
-	var message = fmtDuration(time.Since(start))
-	// Add training error
-	best, err := gp.BestProgram()
+// BestMultiProgram returns the GP's best obtained MultiProgram. It can only be
+// used if the GP has been trained on a multi-class classification task.
+func (gp GP) BestMultiProgram() (MultiProgram, error) {
+	if len(gp.GA.HallOfFame) == 0 {
+		return MultiProgram{}, errors.New("The GP has not been trained yet")
+	}
+	mp, ok := gp.GA.HallOfFame[0].Genome.(*MultiProgram)
+	if !ok {
+		return MultiProgram{}, errors.New("The GP has not been trained on a multi-class classification task")
+	}
+	return *mp, nil
+}
+
+// simplifiedForm returns a string which is identical for Programs or
+// MultiPrograms that are identical once simplified.
+func simplifiedForm(genome eaopt.Genome) string {
+	switch genome := genome.(type) {
+	case *Program:
+		return genome.Op.Simplify().String()
+	case *MultiProgram:
+		var forms = make([]string, len(genome.Ops))
+		for i, operator := range genome.Ops {
+			forms[i] = operator.Simplify().String()
+		}
+		return strings.Join(forms, "\n")
+	}
+	return ""
+}
+
+// bestIndividuals returns the k best distinct Individuals found in the hall of
+// fame and in the final populations. The first one is the best Program and
+// the other ones are ordered by increasing fitness.
+func (gp GP) bestIndividuals(k int) (eaopt.Individuals, error) {
+	if len(gp.GA.HallOfFame) == 0 {
+		return nil, errors.New("The GP has not been trained yet")
+	}
+	if k < 1 {
+		return nil, errors.New("k should be at least 1")
+	}
+	var candidates = append(eaopt.Individuals{}, gp.GA.HallOfFame...)
+	for _, pop := range gp.GA.Populations {
+		candidates = append(candidates, pop.Individuals...)
+	}
+	// The first member of the hall of fame is always the best Program because
+	// it might have been polished or chosen on the validation set
+	var rest = candidates[1:]
+	sort.SliceStable(rest, func(i, j int) bool {
+		return rest[i].Fitness < rest[j].Fitness
+	})
+	var (
+		best = make(eaopt.Individuals, 0, k)
+		seen = make(map[string]bool)
+	)
+	for _, indi := range candidates {
+		// Skip the hall of fame's placeholders and the invalid Programs
+		if indi.Genome == nil || math.IsNaN(indi.Fitness) {
+			continue
+		}
+		var form = simplifiedForm(indi.Genome)
+		if seen[form] {
+			continue
+		}
+		seen[form] = true
+		if best = append(best, indi); len(best) == k {
+			break
+		}
+	}
+	return best, nil
+}
+
+// BestPrograms returns at most k distinct Programs ordered from best to worst,
+// the first one being the one returned by BestProgram.
+// Programs are taken from the hall of fame, which contains HofSize Programs,
+// and from the final populations. Two Programs are considered identical if
+// they have the same simplified form, in which case only the best one is
+// kept.
+func (gp GP) BestPrograms(k int) ([]Program, error) {
+	best, err := gp.bestIndividuals(k)
 	if err != nil {
-		return ""
+		return nil, err
+	}
+	var progs = make([]Program, len(best))
+	for i, indi := range best {
+		prog, ok := indi.Genome.(*Program)
+		if !ok {
+			return nil, errors.New("The GP has been trained on a multi-class classification task, use BestMultiPrograms instead")
+		}
+		progs[i] = *prog
 	}
-	yTrainPred, err := best.Predict(gp.X, gp.EvalMetric.NeedsProbabilities())
+	return progs, nil
+}
+
+// BestMultiPrograms is the same as BestPrograms but for multi-class
+// classification tasks.
+func (gp GP) BestMultiPrograms(k int) ([]MultiProgram, error) {
+	best, err := gp.bestIndividuals(k)
 	if err != nil {
-		return ""
+		return nil, err
+	}
+	var mps = make([]MultiProgram, len(best))
+	for i, indi := range best {
+		mp, ok := indi.Genome.(*MultiProgram)
+		if !ok {
+			return nil, errors.New("The GP has not been trained on a multi-class classification task")
+		}
+		mps[i] = *mp
+	}
+	return mps, nil
+}
+
+// A ParetoPoint contains the objectives of a member of the Pareto front.
+type ParetoPoint struct {
+	Loss       float64
+	Complexity float64
+}
+
+func (gp *GP) paretoPoints() (eaopt.Individuals, []ParetoPoint, error) {
+	if len(gp.GA.Populations) == 0 {
+		return nil, nil, errors.New("The GP has not been trained yet")
+	}
+	var (
+		front, complexities = gp.paretoFront()
+		points              = make([]ParetoPoint, len(front))
+	)
+	for i, indi := range front {
+		points[i] = ParetoPoint{Loss: indi.Fitness, Complexity: complexities[i]}
 	}
-	trainScore, err := gp.EvalMetric.Apply(gp.Y, yTrainPred, nil)
+	return front, points, nil
+}
+
+// ParetoFront returns the Programs of the final populations that are not
+// dominated by any other Program in terms of loss and complexity. The Programs
+// are ordered by increasing complexity and the i-th ParetoPoint contains the
+// objectives of the i-th Program. It is mostly meant to be used with
+// MultiObjective but works in any case.
+func (gp *GP) ParetoFront() ([]Program, []ParetoPoint, error) {
+	front, points, err := gp.paretoPoints()
 	if err != nil {
-		return ""
+		return nil, nil, err
+	}
+	var progs = make([]Program, len(front))
+	for i, indi := range front {
+		prog, ok := indi.Genome.(*Program)
+		if !ok {
+			return nil, nil, errors.New("The GP has been trained on a multi-class classification task, use MultiParetoFront instead")
+		}
+		progs[i] = *prog
+	}
+	return progs, points, nil
+}
+
+// MultiParetoFront is the same as ParetoFront but for multi-class
+// classification tasks.
+func (gp *GP) MultiParetoFront() ([]MultiProgram, []ParetoPoint, error) {
+	front, points, err := gp.paretoPoints()
+	if err != nil {
+		return nil, nil, err
+	}
+	var mps = make([]MultiProgram, len(front))
+	for i, indi := range front {
+		mp, ok := indi.Genome.(*MultiProgram)
+		if !ok {
+			return nil, nil, errors.New("The GP has not been trained on a multi-class classification task")
+		}
+		mps[i] = *mp
+	}
+	return mps, points, nil
+}
+
+// multiClass determines if the GP performs multi-class classification.
+func (gp GP) multiClass() bool {
+	return gp.nClasses > 2
+}
+
+// scores returns the EvalMetric's score of the best Program on the training
+// set and on the validation set. The validation score is NaN if there is no
+// validation set.
+func (gp GP) scores() (train, val float64, err error) {
+	train, err = gp.score(gp.EvalMetric, gp.xFull, gp.yFull, nil)
+	if err != nil {
+		return
 	}
-	message += fmt.Sprintf(", train %s: %.5f", gp.EvalMetric.String(), trainScore)
-	// Add validation error
+	val = math.NaN()
 	if gp.XVal != nil && gp.YVal != nil {
-		yEvalPred, err := best.Predict(gp.XVal, gp.EvalMetric.NeedsProbabilities())
+		val, err = gp.score(gp.EvalMetric, gp.XVal, gp.YVal, gp.WVal)
+		if err != nil {
+			return train, val, err
+		}
+	}
+	return
+}
+
+// score returns a Metric's score of the best Program on a dataset. The best
+// MultiProgram is scored on the probabilities of each class in case of
+// multi-class classification.
+func (gp GP) score(metric metrics.Metric, X [][]float64, Y, W []float64) (float64, error) {
+	if gp.multiClass() {
+		best, err := gp.BestMultiProgram()
 		if err != nil {
-			return ""
+			return 0, err
 		}
-		evalScore, err := gp.EvalMetric.Apply(gp.YVal, yEvalPred, gp.WVal)
+		probas, err := best.PredictProba(X)
 		if err != nil {
-			return ""
+			return 0, err
 		}
-		message += fmt.Sprintf(", val %s: %.5f", gp.EvalMetric.String(), evalScore)
+		return best.applyMetric(metric, probas, Y, W)
+	}
+	yPred, err := gp.Predict(X, metric.NeedsProbabilities())
+	if err != nil {
+		return 0, err
+	}
+	return metric.Apply(Y, yPred, W)
+}
+
+func (gp GP) progress(start time.Time) string {
+	// Add time spent
+	var message = fmtDuration(time.Since(start))
+	// Add training and validation errors
+	train, val, err := gp.scores()
+	if err != nil {
+		return ""
+	}
+	message += fmt.Sprintf(", train %s: %.5f", gp.EvalMetric.String(), train)
+	if !math.IsNaN(val) {
+		message += fmt.Sprintf(", val %s: %.5f", gp.EvalMetric.String(), val)
+	}
+	// Add the cache hit rate
+	if gp.cache != nil {
+		message += fmt.Sprintf(", cache hits: %.1f%%", 100*gp.cache.hitRate())
 	}
 	return message
 }
 
 // polishBest takes the best Program and polishes it.
 func (gp *GP) polishBest() error {
-	best, err := gp.BestProgram()
-	if err != nil {
-		return err
-	}
-	polished, err := polishProgram(best, gp.RNG)
-	if err != nil {
-		return err
+	var polished eaopt.Genome
+	if gp.multiClass() {
+		best, err := gp.BestMultiProgram()
+		if err != nil {
+			return err
+		}
+		mp, err := polishMultiProgram(best, gp.RNG)
+		if err != nil {
+			return err
+		}
+		polished = &mp
+	} else {
+		best, err := gp.BestProgram()
+		if err != nil {
+			return err
+		}
+		prog, err := polishProgram(best, gp.RNG)
+		if err != nil {
+			return err
+		}
+		polished = &prog
 	}
 	fitness, err := polished.Evaluate()
 	if err != nil {
 		return err
 	}
 	if fitness < gp.GA.HallOfFame[0].Fitness {
-		gp.GA.HallOfFame[0].Genome = &polished
+		gp.GA.HallOfFame[0].Genome = polished
+		gp.GA.HallOfFame[0].Fitness = fitness
+	}
+	return nil
+}
+
+// earlyStop is called by the GA before each generation. The first call happens
+// once the initial populations have been evaluated, which is when the seeds
+// are injected or when the checkpoint is loaded, after which the GA's Callback
+// is called in order to monitor the initial populations. The following calls
+// save checkpoints. Errors are stored in fitErr because the GA doesn't expect
+// any. The GA is stopped once NGenerations have been reached, which might
+// happen earlier than usual when resuming, if the context is done, if the
+// budget of evaluations is exhausted or if the validation score has stopped
+// improving.
+func (gp *GP) earlyStop(ga *eaopt.GA) bool {
+	if !gp.started {
+		gp.started = true
+		gp.trackRNGs(ga)
+		var resumed bool
+		if gp.Resume {
+			resumed, gp.fitErr = gp.loadCheckpoint(ga)
+		}
+		if gp.fitErr == nil && !resumed {
+			gp.fitErr = gp.injectSeeds(ga)
+		}
+		if ga.Callback != nil {
+			ga.Callback(ga)
+		}
+	} else if gp.CheckpointPath != "" && ga.Generations%gp.CheckpointEvery == 0 {
+		gp.fitErr = gp.saveCheckpoint(ga)
+	}
+	var stop = gp.fitErr != nil || gp.ctx.Err() != nil || gp.budgetExhausted() ||
+		gp.valPlateaued(ga) || ga.Generations >= gp.NGenerations
+	if !stop {
+		gp.fitErr = gp.resample(ga)
+	}
+	return stop || gp.fitErr != nil
+}
+
+// injectSeeds replaces the worst individuals of each population with the
+// seeds. The seeds are spread evenly across the populations. In case of
+// multi-class classification each seed is used for one class, in turn, and
+// the Operators of the other classes are generated randomly.
+func (gp *GP) injectSeeds(ga *eaopt.GA) error {
+	for i, seed := range gp.Seeds {
+		var (
+			pop    = &ga.Populations[i%len(ga.Populations)]
+			genome eaopt.Genome
+		)
+		if gp.multiClass() {
+			var mp = gp.newMultiProgram(pop.RNG)
+			mp.Ops[i%len(mp.Ops)] = seed
+			genome = &mp
+		} else {
+			genome = &Program{GP: gp, Op: seed}
+		}
+		var indi = eaopt.NewIndividual(genome, pop.RNG)
+		if err := indi.Evaluate(); err != nil {
+			return err
+		}
+		pop.Individuals[len(pop.Individuals)-1-i/len(ga.Populations)] = indi
+		updateHallOfFame(ga, indi, pop.RNG)
+	}
+	for _, pop := range ga.Populations {
+		pop.Individuals.SortByFitness()
 	}
 	return nil
 }
@@ -116,23 +493,68 @@ func (gp *GP) Fit(
 	WVal []float64,
 	verbose bool,
 ) error {
+	return gp.FitContext(context.Background(), X, Y, W, XVal, YVal, WVal, verbose)
+}
+
+// FitContext is the same as Fit except that the GA stops evolving once ctx is
+// done. Cancellation is checked between generations, as are MaxDuration and
+// MaxEvaluations. If ctx is done or if a budget is exhausted then the best
+// Program is not polished and no error is returned; the best Program found so
+// far can then be used as usual.
+func (gp *GP) FitContext(
+	ctx context.Context,
+	// Required arguments
+	X [][]float64,
+	Y []float64,
+	// Optional arguments (can safely be nil)
+	W []float64,
+	XVal [][]float64,
+	YVal []float64,
+	WVal []float64,
+	verbose bool,
+) error {
 
 	// Set the training set
 	gp.X = X
 	gp.Y = Y
 	gp.W = W
+	gp.xFull = X
+	gp.yFull = Y
+	gp.wFull = W
+	gp.rows = nil
 
 	// Set the validation set
 	gp.XVal = XVal
 	gp.YVal = YVal
 	gp.WVal = WVal
 
-	// Count the number of classes if the task is classification
+	// Determine the classes if the task is classification
 	if gp.LossMetric.Classification() {
-		// Check that the task to perform is not multi-class classification
-		gp.nClasses = countDistinct(Y)
-		if gp.nClasses > 2 {
-			return errors.New("Multi-class classification is not supported")
+		gp.classes = sortedDistinct(Y)
+		gp.nClasses = len(gp.classes)
+		// Multi-class classification applies the softmax function to the
+		// outputs of one Operator per class, hence the metrics which require
+		// probabilities have to handle the probabilities of each class
+		if gp.multiClass() {
+			for _, metric := range []metrics.Metric{gp.LossMetric, gp.EvalMetric} {
+				if _, ok := metric.(metrics.ProbaMetric); metric.NeedsProbabilities() && !ok {
+					return fmt.Errorf("The '%s' metric can't be used for multi-class classification", metric.String())
+				}
+			}
+			// The Consts of the Operators of a MultiProgram are optimized
+			// together with CMA-ES
+			if gp.PolishMethod == "lm" || gp.PolishMethod == "bfgs" {
+				return fmt.Errorf("The '%s' polishing method can't be used for multi-class classification", gp.PolishMethod)
+			}
+		}
+	}
+
+	// Check the seeds only use available features
+	for _, seed := range gp.Seeds {
+		for _, idx := range op.GetVars(seed) {
+			if int(idx) >= len(X) {
+				return fmt.Errorf("The seed %s uses x%d but there are only %d features", seed, idx, len(X))
+			}
 		}
 	}
 
@@ -157,21 +579,86 @@ func (gp *GP) Fit(
 		})
 		// Make sure the progress bar will stop
 		defer func() { progress.Stop() }()
-		// Use a callback to increment the progress bar at each generation
-		gp.GA.Callback = func(ga *eaopt.GA) { bar.Incr() }
+	}
+
+	// Use a callback to increment the progress bar and to monitor each
+	// generation. The GA calls it once the initial populations have been
+	// evaluated, which is before the seeds are injected or the checkpoint is
+	// loaded, hence it is skipped until earlyStop has done so and calls it
+	gp.GA.Callback = func(ga *eaopt.GA) {
+		if !gp.started {
+			return
+		}
+		if bar != nil {
+			bar.Set(int(ga.Generations))
+		}
+		gp.monitor(ga)
 	}
 
 	// Run the GA
+	if gp.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, gp.MaxDuration)
+		defer cancel()
+	}
+	gp.nEvaluations = new(uint64)
+	gp.cache = nil
+	if gp.CacheSize > 0 {
+		gp.cache = newFitnessCache(int(gp.CacheSize))
+	}
+	// The initial populations are evaluated on a subset of the rows if
+	// RowFraction is below 1
+	gp.sampleRows(0, gp.RNG)
+	gp.prepareTrain()
+	gp.start = time.Now()
+	gp.ctx = ctx
+	gp.started = false
+	gp.bestVal = nil
+	gp.GA.EarlyStop = gp.earlyStop
 	err := gp.GA.Minimize(func(rng *rand.Rand) eaopt.Genome {
+		if gp.multiClass() {
+			var mp = gp.newMultiProgram(rng)
+			return &mp
+		}
 		var prog = gp.newProgram(rng)
 		return &prog
 	})
 	if err != nil {
 		return err
 	}
+	// The GA doesn't check for early stopping if there are no generations
+	if !gp.started {
+		gp.earlyStop(gp.GA)
+	}
+	if gp.fitErr != nil {
+		return gp.fitErr
+	}
+	// The checkpoint is saved before the final Programs are scored on the
+	// full training set so that resuming gives the same results as if there
+	// had been no interruption
+	if gp.CheckpointPath != "" {
+		if err := gp.saveCheckpoint(gp.GA); err != nil {
+			return err
+		}
+	}
+	if err := gp.rescore(gp.GA); err != nil {
+		return err
+	}
+
+	// Use the Program with the best validation score
+	if gp.UseBestValidation && gp.bestVal != nil {
+		gp.GA.HallOfFame[0] = gp.bestVal.indi
+		// The Program might have been scored on a subset of the rows
+		if gp.RowFraction < 1 {
+			gp.GA.HallOfFame[0].Evaluated = false
+			if err := gp.GA.HallOfFame[0].Evaluate(); err != nil {
+				return err
+			}
+		}
+	}
 
 	// Polish the best Program
-	if gp.PolishBest {
+	if gp.PolishBest && ctx.Err() == nil && !gp.budgetExhausted() {
 		err := gp.polishBest()
 		if err != nil {
 			return err
@@ -184,8 +671,17 @@ func (gp *GP) Fit(
 	return nil
 }
 
-// Predict makes predictions with the best obtained Program as so far.
+// Predict makes predictions with the best obtained Program as so far. In case
+// of multi-class classification an error is returned if proba is true, in
+// which case PredictProba has to be used instead.
 func (gp GP) Predict(X [][]float64, proba bool) ([]float64, error) {
+	if gp.multiClass() {
+		var best, err = gp.BestMultiProgram()
+		if err != nil {
+			return nil, err
+		}
+		return best.Predict(X, proba)
+	}
 	var best, err = gp.BestProgram()
 	if err != nil {
 		return nil, err
@@ -194,13 +690,43 @@ func (gp GP) Predict(X [][]float64, proba bool) ([]float64, error) {
 }
 
 // PredictPartial is a convenience function on top of Predict to make
-// predictions on a single instance.
+// predictions on a single instance. Like Predict, it returns an error for
+// multi-class probabilities.
 func (gp GP) PredictPartial(x []float64, proba bool) (float64, error) {
-	var best, err = gp.BestProgram()
+	var X = make([][]float64, len(x))
+	for i, xi := range x {
+		X[i] = []float64{xi}
+	}
+	yPred, err := gp.Predict(X, proba)
 	if err != nil {
 		return 0, err
 	}
-	return best.PredictPartial(x, proba)
+	return yPred[0], nil
+}
+
+// PredictProba returns the probability of each class in case of
+// classification. The output contains one slice per class, ordered by class
+// label.
+func (gp GP) PredictProba(X [][]float64) ([][]float64, error) {
+	if !gp.LossMetric.Classification() {
+		return nil, errors.New("Class probabilities can only be predicted for classification tasks")
+	}
+	if gp.multiClass() {
+		var best, err = gp.BestMultiProgram()
+		if err != nil {
+			return nil, err
+		}
+		return best.PredictProba(X)
+	}
+	p, err := gp.Predict(X, true)
+	if err != nil {
+		return nil, err
+	}
+	var q = make([]float64, len(p))
+	for i, pi := range p {
+		q[i] = 1 - pi
+	}
+	return [][]float64{q, p}, nil
 }
 
 func (gp GP) newConst(rng *rand.Rand) op.Const {
@@ -242,10 +768,22 @@ func (gp GP) newOperator(rng *rand.Rand) op.Operator {
 	)
 }
 
-func (gp GP) newProgram(rng *rand.Rand) Program {
+func (gp *GP) newProgram(rng *rand.Rand) Program {
 	return Program{
 		Op: gp.newOperator(rng),
-		GP: &gp,
+		GP: gp,
+	}
+}
+
+func (gp *GP) newMultiProgram(rng *rand.Rand) MultiProgram {
+	var ops = make([]op.Operator, gp.nClasses)
+	for i := range ops {
+		ops[i] = gp.newOperator(rng)
+	}
+	return MultiProgram{
+		GP:      gp,
+		Ops:     ops,
+		Classes: gp.classes,
 	}
 }
 
@@ -269,3 +807,35 @@ func (gp GP) mutateOperator(operator op.Operator, rng *rand.Rand) op.Operator {
 		return newOp
 	}
 }
+
+// mutate applies one of the GP's mutation operators to an Operator. The
+// mutation operator is chosen randomly according to the GP's mutation
+// probabilities.
+func (gp GP) mutate(operator op.Operator, rng *rand.Rand) op.Operator {
+	var (
+		pHoist   = gp.PHoistMutation
+		pSubtree = gp.PSubtreeMutation
+		pPoint   = gp.PPointMutation
+		dice     = rng.Float64() * (pHoist + pSubtree + pPoint)
+	)
+	switch {
+	// Apply hoist mutation
+	case dice < pHoist:
+		operator = gp.HoistMutation.Apply(operator, rng)
+	// Apply subtree mutation
+	case dice < pHoist+pSubtree:
+		operator = gp.SubtreeMutation.Apply(operator, rng)
+	// Apply point mutation
+	default:
+		operator = gp.PointMutation.Apply(operator, rng)
+	}
+	return gp.simplify(operator)
+}
+
+// simplify simplifies an Operator, it is also normalized if Normalize is set.
+func (gp GP) simplify(operator op.Operator) op.Operator {
+	if gp.Normalize {
+		return op.Normalize(operator)
+	}
+	return operator.Simplify()
+}
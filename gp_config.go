@@ -2,10 +2,12 @@ package xgp
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MaxHalford/eaopt"
 
@@ -20,6 +22,19 @@ type GPConfig struct {
 	EvalMetric     metrics.Metric
 	ParsimonyCoeff float64
 	PolishBest     bool
+	// PolishMethod is one of "cmaes", which is the default, "lm" and "bfgs"
+	PolishMethod string
+	// LinearScaling fits an intercept and a slope to the outputs of each
+	// Program by least squares before computing the loss, it can only be
+	// used for regression
+	LinearScaling bool
+	// Float32 evaluates the Programs with single precision during the
+	// evolution, which is faster and uses less memory on large datasets; the
+	// Consts, the polishing and the predictions remain in double precision
+	Float32 bool
+	// Normalize rewrites the offsprings with op.Normalize instead of only
+	// simplifying them, which makes the Programs shorter
+	Normalize bool
 	// Function parameters
 	Funcs     string
 	ConstMin  float64
@@ -38,6 +53,66 @@ type GPConfig struct {
 	PPointMutation    float64
 	PointMutationRate float64
 	PSubtreeCrossover float64
+	// HofSize is the number of Programs kept in the hall of fame, it
+	// defaults to 1
+	HofSize uint
+	// Selection is one of "tournament", "lexicase", "eps_lexicase" and
+	// "double_tournament"; TournamentSize defaults to 3 and SizePressure is
+	// only used by double tournaments
+	Selection      string
+	TournamentSize uint
+	SizePressure   float64
+	// MultiObjective replaces the GA's model with NSGA-II so that the loss
+	// and the Complexity of the Programs are minimized together, in which
+	// case ParsimonyCoeff is ignored; Complexity defaults to op.CountOps
+	MultiObjective bool
+	Complexity     func(operator op.Operator) float64
+	// Budgets; training stops once either of them is reached, zero values
+	// meaning no limit
+	MaxDuration    time.Duration
+	MaxEvaluations uint64
+	// CacheSize is the maximum number of fitnesses stored in a cache in
+	// order not to evaluate identical Programs more than once, 0 disables
+	// the cache; only the evaluations which miss the cache count towards
+	// MaxEvaluations
+	CacheSize uint
+	// SubtreeCacheMB is the maximum number of megabytes taken up by the
+	// outputs of the subtrees which are memoized when evaluating Programs on
+	// the training set, 0 disables the memoization
+	SubtreeCacheMB uint
+	// Row subsampling; each generation is evaluated on a random proportion
+	// RowFraction of the training rows which is multiplied by
+	// RowFractionGrowth after each generation, until all the rows are used.
+	// The final Programs are scored on all the rows. Zero values default to
+	// 1, which means all the rows are always used
+	RowFraction       float64
+	RowFractionGrowth float64
+	// Memetic parameters; the Consts of a proportion MemeticRate of the
+	// offsprings are optimized during MemeticSteps iterations with the
+	// PolishMethod and MemeticMode is either "lamarckian", which is the
+	// default, or "baldwinian"
+	MemeticRate  float64
+	MemeticSteps uint
+	MemeticMode  string
+	// Early stopping parameters; training stops if the validation score
+	// hasn't improved for NEarlyStoppingGenerations generations and
+	// UseBestValidation replaces the best Program with the one which obtained
+	// the best validation score, both only apply if a validation set is
+	// provided
+	NEarlyStoppingGenerations uint
+	UseBestValidation         bool
+	// Seeds are injected into the initial populations in place of randomly
+	// generated Operators
+	Seeds []op.Operator
+	// Checkpointing parameters; the state of the GA is saved to
+	// CheckpointPath every CheckpointEvery generations and Resume indicates
+	// if the GA should continue from the checkpoint
+	CheckpointPath  string
+	CheckpointEvery uint
+	Resume          bool
+	// Callback is called with the statistics of the initial populations and
+	// then after each generation
+	Callback func(stats GenerationStats)
 	// Other
 	RNG *rand.Rand
 }
@@ -81,16 +156,63 @@ func (c GPConfig) String() string {
 // NewGP returns an GP from a GPConfig.
 func (c GPConfig) NewGP() (*GP, error) {
 
+	// Check the seeds can fit in the initial populations
+	if uint(len(c.Seeds)) > c.NPopulations*c.NIndividuals {
+		return nil, fmt.Errorf("There are %d seeds but only %d individuals",
+			len(c.Seeds), c.NPopulations*c.NIndividuals)
+	}
+
+	// Check the checkpointing parameters
+	if c.CheckpointPath != "" && c.CheckpointEvery == 0 {
+		return nil, errors.New("CheckpointEvery has to be strictly positive")
+	}
+	if c.Resume && c.CheckpointPath == "" {
+		return nil, errors.New("A CheckpointPath is required to resume")
+	}
+
+	// Check the row sampling parameters
+	if c.RowFraction < 0 || c.RowFraction > 1 {
+		return nil, errors.New("RowFraction should be between 0 and 1")
+	}
+	if c.RowFractionGrowth != 0 && c.RowFractionGrowth < 1 {
+		return nil, errors.New("RowFractionGrowth should be at least 1")
+	}
+
+	// Check the memetic parameters
+	if c.MemeticRate < 0 || c.MemeticRate > 1 {
+		return nil, errors.New("MemeticRate should be between 0 and 1")
+	}
+	if c.MemeticMode != "" && c.MemeticMode != "lamarckian" && c.MemeticMode != "baldwinian" {
+		return nil, fmt.Errorf("Unknown memetic mode '%s', has to be 'lamarckian' or 'baldwinian'", c.MemeticMode)
+	}
+	// The residuals obtained with Baldwinian learning are used by lexicase
+	// selection but they aren't checkpointed
+	if c.Resume && c.MemeticRate > 0 && c.MemeticMode == "baldwinian" &&
+		(c.Selection == "lexicase" || c.Selection == "eps_lexicase") {
+		return nil, errors.New("Resuming isn't possible with Baldwinian learning and lexicase selection")
+	}
+
 	// Default the evaluation metric to the fitness metric if it's nil
 	if c.EvalMetric == nil {
 		c.EvalMetric = c.LossMetric
 	}
 
 	// The convention is to use a fitness metric which has to be minimized
+	var loss = c.LossMetric
 	if c.LossMetric.BiggerIsBetter() {
 		c.LossMetric = metrics.Negative{Metric: c.LossMetric}
 	}
 
+	// Linear scaling only makes sense for regression
+	if c.LinearScaling && loss.Classification() {
+		return nil, fmt.Errorf("Linear scaling can't be used with the '%s' metric because it is a classification metric", loss.String())
+	}
+
+	// Check the polishing method
+	if err := checkPolishMethod(c.PolishMethod, loss); err != nil {
+		return nil, err
+	}
+
 	// Determine the functions to use
 	functions, err := op.ParseFuncs(c.Funcs, ",")
 	if err != nil {
@@ -112,19 +234,56 @@ func (c GPConfig) NewGP() (*GP, error) {
 		},
 	}
 
+	// Keep at least the best Program
+	if estimator.HofSize == 0 {
+		estimator.HofSize = 1
+	}
+
+	// Use all the rows by default
+	if estimator.RowFraction == 0 {
+		estimator.RowFraction = 1
+	}
+	if estimator.RowFractionGrowth == 0 {
+		estimator.RowFractionGrowth = 1
+	}
+
+	// Determine the selection method
+	if estimator.TournamentSize == 0 {
+		estimator.TournamentSize = 3
+	}
+	if estimator.SizePressure == 0 {
+		estimator.SizePressure = 1.4
+	}
+	if estimator.MemeticSteps == 0 {
+		estimator.MemeticSteps = 5
+	}
+	selector, err := estimator.newSelector(loss)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine the GA's model, NSGA-II having it's own selection method
+	var model eaopt.Model = gaModel{
+		gp:         estimator,
+		selector:   selector,
+		pMutate:    c.PHoistMutation + c.PPointMutation + c.PSubtreeMutation,
+		pCrossover: c.PSubtreeCrossover,
+	}
+	if c.MultiObjective {
+		model = nsga2Model{
+			gp:         estimator,
+			pMutate:    c.PHoistMutation + c.PPointMutation + c.PSubtreeMutation,
+			pCrossover: c.PSubtreeCrossover,
+		}
+	}
+
 	// Set the initial GA
 	estimator.GA, err = eaopt.GAConfig{
 		NPops:        c.NPopulations,
 		PopSize:      c.NIndividuals,
 		NGenerations: c.NGenerations,
-		HofSize:      1,
-		Model: gaModel{
-			selector: eaopt.SelTournament{
-				NContestants: 3,
-			},
-			pMutate:    c.PHoistMutation + c.PPointMutation + c.PSubtreeMutation,
-			pCrossover: c.PSubtreeCrossover,
-		},
+		HofSize:      estimator.HofSize,
+		Model:        model,
 		RNG:          c.RNG,
 		ParallelEval: true,
 	}.NewGA()
@@ -198,6 +357,7 @@ func NewDefaultGPConfig() GPConfig {
 		EvalMetric:     nil,
 		ParsimonyCoeff: 0,
 		PolishBest:     true,
+		PolishMethod:   "cmaes",
 
 		Funcs:     "add,sub,mul,div",
 		ConstMin:  -5,
@@ -216,5 +376,16 @@ func NewDefaultGPConfig() GPConfig {
 		PSubtreeMutation:  0.1,
 		PointMutationRate: 0.3,
 		PSubtreeCrossover: 0.5,
+		HofSize:           1,
+		Selection:         "tournament",
+		TournamentSize:    3,
+		SizePressure:      1.4,
+		MemeticRate:       0,
+		MemeticSteps:      5,
+		MemeticMode:       "lamarckian",
+
+		RowFraction:       1,
+		RowFractionGrowth: 1,
+		CheckpointEvery:   1,
 	}
 }
@@ -1,10 +1,18 @@
 package xgp
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/MaxHalford/xgp/metrics"
+	"github.com/MaxHalford/xgp/op"
 )
 
 func TestGP(t *testing.T) {
@@ -72,3 +80,612 @@ func TestGPProgress(t *testing.T) {
 		t.Errorf("Expected %s, got %s", expected, progress)
 	}
 }
+
+func TestGPSeeds(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4},
+			[]float64{4, 5, 6, 7},
+		}
+		Y          = []float64{7, 13, 21, 31}
+		formula, _ = op.ParseExpr("x0*x1+3")
+		testCases  = []struct {
+			seeds        []op.Operator
+			nGenerations uint
+			raisesError  bool
+		}{
+			{
+				seeds:        []op.Operator{formula},
+				nGenerations: 0,
+				raisesError:  false,
+			},
+			{
+				seeds:        []op.Operator{op.Var{0}, formula},
+				nGenerations: 3,
+				raisesError:  false,
+			},
+			{
+				seeds:        []op.Operator{op.Add{formula, op.Var{2}}},
+				nGenerations: 3,
+				raisesError:  true,
+			},
+		}
+	)
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var conf = NewDefaultGPConfig()
+			conf.RNG = rand.New(rand.NewSource(42))
+			conf.NIndividuals = 30
+			conf.NGenerations = tc.nGenerations
+			conf.MinHeight = 1
+			conf.MaxHeight = 2
+			conf.PolishBest = false
+			conf.Seeds = tc.seeds
+			var gp, err = conf.NewGP()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			err = gp.Fit(X, Y, nil, nil, nil, nil, false)
+			if (err != nil) != tc.raisesError {
+				t.Errorf("Expected error %t, got %v", tc.raisesError, err)
+				return
+			}
+			if tc.raisesError {
+				return
+			}
+			if fitness := gp.GA.HallOfFame[0].Fitness; fitness != 0 {
+				t.Errorf("Expected 0, got %.5f", fitness)
+			}
+		})
+	}
+}
+
+func TestGPTooManySeeds(t *testing.T) {
+	var conf = NewDefaultGPConfig()
+	conf.NPopulations = 1
+	conf.NIndividuals = 2
+	conf.Seeds = []op.Operator{op.Var{0}, op.Var{1}, op.Var{2}}
+	if _, err := conf.NewGP(); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
+
+func TestGPResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xgp")
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y    = []float64{7, 13, 21, 31, 43}
+		XVal = [][]float64{
+			[]float64{2, 4, 6},
+			[]float64{1, 3, 5},
+		}
+		YVal      = []float64{5, 14, 27}
+		testCases = []struct {
+			configure   func(conf *GPConfig)
+			raisesError bool
+		}{
+			{
+				configure:   func(conf *GPConfig) {},
+				raisesError: false,
+			},
+			{
+				configure: func(conf *GPConfig) {
+					conf.RowFraction = 0.4
+					conf.RowFractionGrowth = 1.2
+				},
+				raisesError: false,
+			},
+			{
+				configure: func(conf *GPConfig) {
+					conf.NEarlyStoppingGenerations = 2
+					conf.UseBestValidation = true
+				},
+				raisesError: false,
+			},
+			{
+				configure: func(conf *GPConfig) {
+					conf.PolishMethod = "lm"
+					conf.MemeticRate = 0.5
+					conf.MemeticMode = "baldwinian"
+				},
+				raisesError: false,
+			},
+			{
+				configure: func(conf *GPConfig) {
+					conf.PolishMethod = "lm"
+					conf.MemeticRate = 0.5
+					conf.MemeticMode = "baldwinian"
+					conf.Selection = "lexicase"
+				},
+				raisesError: true,
+			},
+		}
+	)
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var (
+				path = filepath.Join(dir, fmt.Sprintf("checkpoint_%d.json", i))
+				fit  = func(seed int64, nGenerations uint, checkpointPath string, resume bool) (*GP, error) {
+					var conf = NewDefaultGPConfig()
+					conf.RNG = rand.New(rand.NewSource(seed))
+					conf.NPopulations = 2
+					conf.NIndividuals = 20
+					conf.NGenerations = nGenerations
+					conf.MinHeight = 1
+					conf.MaxHeight = 3
+					conf.CheckpointPath = checkpointPath
+					conf.CheckpointEvery = 2
+					conf.Resume = resume
+					tc.configure(&conf)
+					var gp, err = conf.NewGP()
+					if err != nil {
+						return nil, err
+					}
+					return gp, gp.Fit(X, Y, nil, XVal, YVal, nil, false)
+				}
+			)
+
+			// Run the GA without interruption
+			uninterrupted, err := fit(42, 7, "", false)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+
+			// Run the GA for a few generations and then resume it with another
+			// seed
+			if _, err = fit(42, 3, path, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			resumed, err := fit(1337, 7, path, true)
+			if (err != nil) != tc.raisesError {
+				t.Errorf("Expected error %t, got %v", tc.raisesError, err)
+				return
+			}
+			if tc.raisesError {
+				return
+			}
+
+			if resumed.GA.Generations != uninterrupted.GA.Generations {
+				t.Errorf("Expected %d generations, got %d", uninterrupted.GA.Generations, resumed.GA.Generations)
+			}
+			if resumed.NEvaluations() != uninterrupted.NEvaluations() {
+				t.Errorf("Expected %d evaluations, got %d", uninterrupted.NEvaluations(), resumed.NEvaluations())
+			}
+			best1, _ := uninterrupted.BestProgram()
+			best2, _ := resumed.BestProgram()
+			if best1.String() != best2.String() {
+				t.Errorf("Expected %s, got %s", best1, best2)
+			}
+			for i, pop := range uninterrupted.GA.Populations {
+				for j, indi := range pop.Individuals {
+					var indi2 = resumed.GA.Populations[i].Individuals[j]
+					if indi.Fitness != indi2.Fitness || indi.ID != indi2.ID {
+						t.Errorf("Expected identical populations")
+						return
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGPFitContext(t *testing.T) {
+	var conf = NewDefaultGPConfig()
+	conf.RNG = rand.New(rand.NewSource(42))
+	conf.NIndividuals = 30
+	var gp, err = conf.NewGP()
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3},
+			[]float64{4, 5, 6},
+		}
+		Y           = []float64{5, 7, 9}
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	cancel()
+	if err = gp.FitContext(ctx, X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if gp.GA.Generations != 0 {
+		t.Errorf("Expected 0 generations, got %d", gp.GA.Generations)
+	}
+	if _, err = gp.BestProgram(); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+	}
+}
+
+func TestGPBudgets(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3},
+			[]float64{4, 5, 6},
+		}
+		Y         = []float64{5, 7, 9}
+		testCases = []struct {
+			maxDuration    time.Duration
+			maxEvaluations uint64
+		}{
+			{
+				maxDuration:    0,
+				maxEvaluations: 50,
+			},
+			{
+				maxDuration:    time.Nanosecond,
+				maxEvaluations: 0,
+			},
+		}
+	)
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var conf = NewDefaultGPConfig()
+			conf.RNG = rand.New(rand.NewSource(42))
+			conf.NIndividuals = 20
+			conf.NGenerations = 1000
+			conf.MaxDuration = tc.maxDuration
+			conf.MaxEvaluations = tc.maxEvaluations
+			var gp, err = conf.NewGP()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if gp.GA.Generations >= conf.NGenerations {
+				t.Errorf("Expected less than %d generations, got %d", conf.NGenerations, gp.GA.Generations)
+			}
+			if tc.maxEvaluations > 0 && gp.NEvaluations() >= tc.maxEvaluations+uint64(conf.NIndividuals) {
+				t.Errorf("Expected less than %d evaluations, got %d",
+					tc.maxEvaluations+uint64(conf.NIndividuals), gp.NEvaluations())
+			}
+			if _, err = gp.BestProgram(); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+			}
+		})
+	}
+}
+
+func TestGPBestPrograms(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y    = []float64{7, 13, 21, 31, 43}
+		conf = NewDefaultGPConfig()
+	)
+	conf.RNG = rand.New(rand.NewSource(42))
+	conf.NIndividuals = 30
+	conf.NGenerations = 5
+	conf.HofSize = 5
+	conf.PolishBest = false
+	var gp, err = conf.NewGP()
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if _, err = gp.BestPrograms(3); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(gp.GA.HallOfFame) != 5 {
+		t.Errorf("Expected a hall of fame of size 5, got %d", len(gp.GA.HallOfFame))
+	}
+	progs, err := gp.BestPrograms(10)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(progs) != 10 {
+		t.Errorf("Expected 10 programs, got %d", len(progs))
+		return
+	}
+	best, _ := gp.BestProgram()
+	if progs[0].String() != best.String() {
+		t.Errorf("Expected %s, got %s", best, progs[0])
+	}
+	var seen = make(map[string]bool)
+	for _, prog := range progs {
+		var form = prog.Op.Simplify().String()
+		if seen[form] {
+			t.Errorf("%s is duplicated", form)
+		}
+		seen[form] = true
+	}
+	if _, err = gp.BestPrograms(0); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	if _, err = gp.BestMultiPrograms(1); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
+
+func TestGPCallback(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y         = []float64{7, 13, 21, 31, 43}
+		testCases = []struct {
+			XVal [][]float64
+			YVal []float64
+		}{
+			{XVal: nil, YVal: nil},
+			{XVal: X, YVal: Y},
+		}
+	)
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var (
+				conf  = NewDefaultGPConfig()
+				stats []GenerationStats
+			)
+			conf.RNG = rand.New(rand.NewSource(42))
+			conf.NIndividuals = 30
+			conf.NGenerations = 4
+			conf.Callback = func(s GenerationStats) { stats = append(stats, s) }
+			var gp, err = conf.NewGP()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if err = gp.Fit(X, Y, nil, tc.XVal, tc.YVal, nil, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if len(stats) != int(conf.NGenerations)+1 {
+				t.Errorf("Expected %d calls, got %d", conf.NGenerations+1, len(stats))
+				return
+			}
+			for j, s := range stats {
+				if s.Generation != uint(j) {
+					t.Errorf("Expected generation %d, got %d", j, s.Generation)
+				}
+				if s.BestFitness > s.MedianFitness || s.MedianFitness > s.WorstFitness {
+					t.Errorf("Expected best <= median <= worst, got %v", s)
+				}
+				if s.NDistinct < 1 || s.NDistinct > int(conf.NIndividuals) {
+					t.Errorf("Expected between 1 and %d distinct programs, got %d", conf.NIndividuals, s.NDistinct)
+				}
+				if s.MeanSize < 1 || s.MeanHeight < 0 {
+					t.Errorf("Expected positive shapes, got %v", s)
+				}
+				if math.IsNaN(s.ValScore) != (tc.XVal == nil) {
+					t.Errorf("Unexpected validation score %f", s.ValScore)
+				}
+			}
+		})
+	}
+}
+
+func TestGPEarlyStopping(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y         = []float64{7, 13, 21, 31, 43}
+		XVal      = [][]float64{[]float64{6, 7}, []float64{9, 10}}
+		YVal      = []float64{80, 40}
+		testCases = []struct {
+			XVal              [][]float64
+			YVal              []float64
+			useBestValidation bool
+			stopsEarly        bool
+		}{
+			{XVal: nil, YVal: nil, useBestValidation: false, stopsEarly: false},
+			{XVal: XVal, YVal: YVal, useBestValidation: false, stopsEarly: true},
+			{XVal: XVal, YVal: YVal, useBestValidation: true, stopsEarly: true},
+		}
+	)
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var conf = NewDefaultGPConfig()
+			conf.RNG = rand.New(rand.NewSource(42))
+			conf.NIndividuals = 30
+			conf.NGenerations = 50
+			conf.PolishBest = false
+			conf.NEarlyStoppingGenerations = 3
+			conf.UseBestValidation = tc.useBestValidation
+			var gp, err = conf.NewGP()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if err = gp.Fit(X, Y, nil, tc.XVal, tc.YVal, nil, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if (gp.GA.Generations < conf.NGenerations) != tc.stopsEarly {
+				t.Errorf("Unexpected number of generations %d", gp.GA.Generations)
+				return
+			}
+			if !tc.stopsEarly {
+				return
+			}
+			if gp.GA.Generations-gp.bestVal.generation != conf.NEarlyStoppingGenerations {
+				t.Errorf("Expected to stop %d generations after generation %d, stopped at generation %d",
+					conf.NEarlyStoppingGenerations, gp.bestVal.generation, gp.GA.Generations)
+			}
+			best, _ := gp.BestProgram()
+			if tc.useBestValidation && best.String() != gp.bestVal.indi.Genome.(*Program).String() {
+				t.Errorf("Expected %s, got %s", gp.bestVal.indi.Genome, best)
+			}
+		})
+	}
+}
+
+func TestGPLinearScaling(t *testing.T) {
+	var (
+		X = [][]float64{[]float64{1, 2, 3, 4, 5, 6}}
+		Y = []float64{1300, 1600, 1900, 2200, 2500, 2800}
+	)
+	for _, method := range []string{"cmaes", "lm", "bfgs"} {
+		t.Run(method, func(t *testing.T) {
+			var conf = NewDefaultGPConfig()
+			conf.RNG = rand.New(rand.NewSource(42))
+			conf.LossMetric = metrics.MSE{}
+			conf.NIndividuals = 30
+			conf.NGenerations = 10
+			conf.PolishMethod = method
+			conf.LinearScaling = true
+			var gp, err = conf.NewGP()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			prog, err := gp.BestProgram()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if prog.Scaling == nil {
+				t.Error("Expected a Scaling, got nil")
+				return
+			}
+			yPred, err := prog.Predict(X, false)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			for i, y := range yPred {
+				if math.Abs(y-Y[i]) > 10e-5 {
+					t.Errorf("Expected %.5f, got %.5f", Y[i], y)
+				}
+			}
+		})
+	}
+	// Linear scaling can't be used for classification
+	var conf = NewDefaultGPConfig()
+	conf.LossMetric = metrics.LogLoss{}
+	conf.LinearScaling = true
+	if _, err := conf.NewGP(); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
+
+func TestGPFloat32(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5, 6},
+			[]float64{0.5, 0.1, 0.7, 0.3, 0.2, 0.9},
+		}
+		Y = []float64{2.5, 4.1, 6.7, 8.3, 10.2, 12.9}
+		W = []float64{1, 2, 1, 2, 1, 2}
+	)
+	var testCases = []struct {
+		loss          metrics.Metric
+		linearScaling bool
+		selection     string
+	}{
+		{metrics.MSE{}, false, "tournament"},
+		{metrics.MAE{}, true, "tournament"},
+		{metrics.MSE{}, false, "lexicase"},
+		{metrics.MAE{}, true, "eps_lexicase"},
+		{metrics.R2{}, false, "tournament"},
+		{metrics.AbsolutePearson{}, false, "tournament"},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var conf = NewDefaultGPConfig()
+			conf.RNG = rand.New(rand.NewSource(42))
+			conf.LossMetric = tc.loss
+			conf.EvalMetric = tc.loss
+			conf.LinearScaling = tc.linearScaling
+			conf.Selection = tc.selection
+			conf.NIndividuals = 30
+			conf.NGenerations = 5
+			conf.Float32 = true
+			var gp, err = conf.NewGP()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if err = gp.Fit(X, Y, W, nil, nil, nil, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			// The fitness obtained with single precision has to be close
+			// to the one obtained with double precision
+			prog, err := gp.BestProgram()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			gp.x32 = nil
+			fitness, err := prog.evaluate()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if math.Abs(fitness-gp.GA.HallOfFame[0].Fitness) > 1e-4*math.Max(1, math.Abs(fitness)) {
+				t.Errorf("Expected %f, got %f", fitness, gp.GA.HallOfFame[0].Fitness)
+			}
+		})
+	}
+}
+
+func TestGPNormalize(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y = []float64{7, 13, 21, 31, 43}
+	)
+	var conf = NewDefaultGPConfig()
+	conf.RNG = rand.New(rand.NewSource(42))
+	conf.NIndividuals = 20
+	conf.NGenerations = 5
+	conf.PolishBest = false
+	conf.Normalize = true
+	var gp, err = conf.NewGP()
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	// The offsprings have to be normalized
+	var rng = rand.New(rand.NewSource(42))
+	for i := 0; i < 20; i++ {
+		var (
+			prog1 = gp.newProgram(rng)
+			prog2 = gp.newProgram(rng)
+		)
+		prog1.Mutate(rng)
+		prog2.Crossover(&prog1, rng)
+		for _, prog := range []Program{prog1, prog2} {
+			if normalized := op.Normalize(prog.Op); normalized != prog.Op {
+				t.Errorf("Expected %s, got %s", normalized, prog.Op)
+			}
+		}
+	}
+}
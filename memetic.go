@@ -0,0 +1,84 @@
+package xgp
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/MaxHalford/eaopt"
+)
+
+// memetic runs a few iterations of local search on the Consts of a fraction
+// of the offsprings. With Lamarckian learning the tuned Consts replace the
+// original ones whereas with Baldwinian learning only the improved fitness is
+// kept. The local searches are run concurrently, each one with it's own
+// random number generator so that the results remain reproducible.
+func (gp *GP) memetic(offsprings eaopt.Individuals, rng *rand.Rand) error {
+	if gp.MemeticRate == 0 {
+		return nil
+	}
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(offsprings))
+	)
+	for i := range offsprings {
+		if rng.Float64() >= gp.MemeticRate {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, rng *rand.Rand) {
+			defer wg.Done()
+			errs[i] = gp.localSearch(&offsprings[i], rng)
+		}(i, rand.New(rand.NewSource(rng.Int63())))
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localSearch tunes the Consts of an Individual. The Individual is left
+// untouched if the local search doesn't improve it's fitness.
+func (gp *GP) localSearch(indi *eaopt.Individual, rng *rand.Rand) error {
+	if err := indi.Evaluate(); err != nil {
+		return err
+	}
+	var tuned eaopt.Genome
+	switch genome := indi.Genome.(type) {
+	case *Program:
+		prog, err := optimizeProgram(*genome, int(gp.MemeticSteps), rng)
+		if err != nil {
+			return err
+		}
+		tuned = &prog
+	case *MultiProgram:
+		mp, err := optimizeMultiProgram(*genome, int(gp.MemeticSteps), rng)
+		if err != nil {
+			return err
+		}
+		tuned = &mp
+	}
+	fitness, err := tuned.Evaluate()
+	if err != nil {
+		return err
+	}
+	if fitness >= indi.Fitness {
+		return nil
+	}
+	indi.Fitness = fitness
+	if gp.MemeticMode == "baldwinian" {
+		// The residuals of the tuned Genome are kept because they are used
+		// for selection in the same way as the fitness
+		switch genome := indi.Genome.(type) {
+		case *Program:
+			genome.residuals = tuned.(*Program).residuals
+		case *MultiProgram:
+			genome.residuals = tuned.(*MultiProgram).residuals
+		}
+		return nil
+	}
+	indi.Genome = tuned
+	return nil
+}
@@ -0,0 +1,84 @@
+package xgp
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/MaxHalford/eaopt"
+
+	"github.com/MaxHalford/xgp/metrics"
+	"github.com/MaxHalford/xgp/op"
+)
+
+func TestLocalSearch(t *testing.T) {
+	var (
+		X = [][]float64{[]float64{1, 2, 3, 4, 5}}
+		Y = []float64{8, 13, 18, 23, 28}
+	)
+	for _, mode := range []string{"lamarckian", "baldwinian"} {
+		t.Run(mode, func(t *testing.T) {
+			var (
+				gp = &GP{
+					GPConfig:   GPConfig{PolishMethod: "lm", MemeticMode: mode, MemeticSteps: 5},
+					X:          X,
+					Y:          Y,
+					LossMetric: metrics.MSE{},
+				}
+				operator = op.Add{op.Const{1}, op.Mul{op.Const{1}, op.Var{0}}}
+				indi     = eaopt.Individual{Genome: &Program{GP: gp, Op: operator}}
+			)
+			if err := gp.localSearch(&indi, rand.New(rand.NewSource(42))); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if indi.Fitness > 1e-6 {
+				t.Errorf("Expected a fitness of 0, got %f", indi.Fitness)
+			}
+			var (
+				prog    = indi.Genome.(*Program)
+				changed = prog.Op.String() != operator.String()
+			)
+			if changed != (mode == "lamarckian") {
+				t.Errorf("Unexpected program %s", prog)
+			}
+		})
+	}
+}
+
+func TestGPMemetic(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y = []float64{7, 13, 21, 31, 43}
+	)
+	for _, multiObjective := range []bool{false, true} {
+		var conf = NewDefaultGPConfig()
+		conf.RNG = rand.New(rand.NewSource(42))
+		conf.NIndividuals = 20
+		conf.NGenerations = 3
+		conf.PolishBest = false
+		conf.PolishMethod = "lm"
+		conf.MemeticRate = 0.5
+		conf.MultiObjective = multiObjective
+		var gp, err = conf.NewGP()
+		if err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+			t.Errorf("Expected nil, got %s", err)
+		}
+	}
+	var conf = NewDefaultGPConfig()
+	conf.MemeticRate = 1.5
+	if _, err := conf.NewGP(); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	conf = NewDefaultGPConfig()
+	conf.MemeticMode = "darwinian"
+	if _, err := conf.NewGP(); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
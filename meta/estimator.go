@@ -0,0 +1,152 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/MaxHalford/xgp"
+)
+
+// An Estimator can be trained on a dataset. Both *xgp.GP and
+// *GradientBoosting are Estimators.
+type Estimator interface {
+	Fit(
+		X [][]float64,
+		Y []float64,
+		W []float64,
+		XVal [][]float64,
+		YVal []float64,
+		WVal []float64,
+		verbose bool,
+	) error
+}
+
+// A ContextEstimator is an Estimator whose training can be stopped with a
+// context.Context. Both *xgp.GP and *GradientBoosting are ContextEstimators.
+type ContextEstimator interface {
+	Estimator
+	FitContext(
+		ctx context.Context,
+		X [][]float64,
+		Y []float64,
+		W []float64,
+		XVal [][]float64,
+		YVal []float64,
+		WVal []float64,
+		verbose bool,
+	) error
+}
+
+// fitContext trains an Estimator with it's FitContext method if it is a
+// ContextEstimator and with it's Fit method otherwise.
+func fitContext(
+	ctx context.Context,
+	est Estimator,
+	X [][]float64,
+	Y []float64,
+	W []float64,
+	XVal [][]float64,
+	YVal []float64,
+	WVal []float64,
+	verbose bool,
+) error {
+	if est, ok := est.(ContextEstimator); ok {
+		return est.FitContext(ctx, X, Y, W, XVal, YVal, WVal, verbose)
+	}
+	return est.Fit(X, Y, W, XVal, YVal, WVal, verbose)
+}
+
+// A Predictor makes predictions once an Estimator has been trained.
+type Predictor interface {
+	Predict(X [][]float64, proba bool) ([]float64, error)
+}
+
+// extractPredictor returns the Predictor obtained by training an Estimator.
+func extractPredictor(est Estimator) (Predictor, error) {
+	switch est := est.(type) {
+	case *xgp.GP:
+		return est.BestProgram()
+	case *GradientBoosting:
+		return est, nil
+	}
+	if pred, ok := est.(Predictor); ok {
+		return pred, nil
+	}
+	return nil, errors.New("The estimator doesn't implement the Predictor interface")
+}
+
+// fitParallel calls fit once for each of the n sub-models, each in a separate
+// goroutine. The first error that is encountered is returned.
+func fitParallel(n int, fit func(i int) error) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fit(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serialPredictor uses the same flavor names as the CLI in order to indicate
+// the type of the serialized Predictor.
+type serialPredictor struct {
+	Flavor string          `json:"flavor"`
+	Model  json.RawMessage `json:"model"`
+}
+
+func serializePredictors(preds []Predictor) ([]serialPredictor, error) {
+	var serials = make([]serialPredictor, len(preds))
+	for i, pred := range preds {
+		switch pred.(type) {
+		case xgp.Program:
+			serials[i].Flavor = "vanilla"
+		case *GradientBoosting, GradientBoosting:
+			serials[i].Flavor = "boosting"
+		default:
+			return nil, fmt.Errorf("Predictors of type %T can't be serialized", pred)
+		}
+		bytes, err := json.Marshal(pred)
+		if err != nil {
+			return nil, err
+		}
+		serials[i].Model = bytes
+	}
+	return serials, nil
+}
+
+func parsePredictors(serials []serialPredictor) ([]Predictor, error) {
+	var preds = make([]Predictor, len(serials))
+	for i, serial := range serials {
+		switch serial.Flavor {
+		case "vanilla":
+			var prog xgp.Program
+			if err := json.Unmarshal(serial.Model, &prog); err != nil {
+				return nil, err
+			}
+			preds[i] = prog
+		case "boosting":
+			var gb = &GradientBoosting{}
+			if err := json.Unmarshal(serial.Model, gb); err != nil {
+				return nil, err
+			}
+			preds[i] = gb
+		default:
+			return nil, fmt.Errorf("Unknown flavor '%s'", serial.Flavor)
+		}
+	}
+	return preds, nil
+}
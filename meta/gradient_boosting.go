@@ -1,7 +1,9 @@
 package meta
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -12,6 +14,10 @@ import (
 )
 
 // GradientBoosting implements gradient boosting on top of genetic programming.
+// In case of multi-class classification one Program per class is trained at
+// each round on the gradient of the multinomial log-loss; the Programs and
+// the steps are then stored in the ClassPrograms and ClassSteps fields instead
+// of the Programs and Steps fields.
 type GradientBoosting struct {
 	xgp.GPConfig
 	NRounds              uint
@@ -27,9 +33,14 @@ type GradientBoosting struct {
 	ValScores            []float64
 	TrainScores          []float64
 	YMean                float64
+	Classes              []float64
+	ClassPriors          []float64
+	ClassPrograms        [][]xgp.Program
+	ClassSteps           [][]float64
 	UseBestRounds        bool
 	MonitorEvery         uint
 	RNG                  *rand.Rand
+	nEvaluations         uint64
 }
 
 // NewGradientBoosting returns a GradientBoosting.
@@ -85,6 +96,57 @@ func sign(y []float64) []float64 {
 	return classes
 }
 
+// softmax returns the probabilities obtained by applying the softmax function
+// to the raw scores of each class.
+func softmax(F [][]float64) [][]float64 {
+	var probas = make([][]float64, len(F))
+	for k := range F {
+		probas[k] = make([]float64, len(F[k]))
+	}
+	for j := range F[0] {
+		var max = math.Inf(-1)
+		for k := range F {
+			max = math.Max(max, F[k][j])
+		}
+		var sum float64
+		for k := range F {
+			probas[k][j] = math.Exp(F[k][j] - max)
+			sum += probas[k][j]
+		}
+		for k := range F {
+			probas[k][j] /= sum
+		}
+	}
+	return probas
+}
+
+func (gb GradientBoosting) multiClass() bool {
+	return len(gb.Classes) > 2
+}
+
+// scoreProba scores class probabilities. ProbaMetrics are applied to the
+// probabilities of all the classes, such as LogLoss which then becomes the
+// multinomial log-loss. The other metrics that need probabilities are applied
+// to each class against the rest of the classes and then averaged, whereas
+// the remaining metrics are applied to the most probable classes.
+func (gb GradientBoosting) scoreProba(metric metrics.Metric, Y []float64, probas [][]float64) (float64, error) {
+	if !metric.NeedsProbabilities() {
+		return metric.Apply(Y, mostProbable(probas, gb.Classes), nil)
+	}
+	if pm, ok := metric.(metrics.ProbaMetric); ok {
+		return pm.ApplyProba(Y, probas, gb.Classes, nil)
+	}
+	var score float64
+	for k, class := range gb.Classes {
+		s, err := metric.Apply(binarize(Y, class), probas[k], nil)
+		if err != nil {
+			return 0, err
+		}
+		score += s
+	}
+	return score / float64(len(gb.Classes)), nil
+}
+
 func (gb GradientBoosting) shouldMonitor(round uint) bool {
 	return round == 0 || (round+1)%gb.MonitorEvery == 0
 }
@@ -112,6 +174,38 @@ func (gb GradientBoosting) bestRound() int {
 	return round
 }
 
+// newGP returns a GP whose budget of evaluations is what remains of the
+// GradientBoosting's budget. The time budget is enforced through the context
+// passed to the GP. The Seeds and the checkpointing parameters are cleared
+// because each GP is trained on different gradients; the Seeds don't fit
+// them and the GPs would otherwise share the same checkpoint.
+func (gb *GradientBoosting) newGP() (*xgp.GP, error) {
+	var conf = gb.GPConfig
+	conf.MaxDuration = 0
+	conf.Seeds = nil
+	conf.CheckpointPath = ""
+	conf.CheckpointEvery = 0
+	conf.Resume = false
+	if gb.MaxEvaluations > 0 {
+		conf.MaxEvaluations = 1
+		if gb.nEvaluations < gb.MaxEvaluations {
+			conf.MaxEvaluations = gb.MaxEvaluations - gb.nEvaluations
+		}
+	}
+	return conf.NewGP()
+}
+
+// NEvaluations returns the number of times a Program has been evaluated
+// during the last call to Fit.
+func (gb GradientBoosting) NEvaluations() uint64 {
+	return gb.nEvaluations
+}
+
+// budgetExhausted indicates if MaxEvaluations has been reached.
+func (gb GradientBoosting) budgetExhausted() bool {
+	return gb.MaxEvaluations > 0 && gb.nEvaluations >= gb.MaxEvaluations
+}
+
 // Fit iteratively trains a GP on the gradient of the loss.
 func (gb *GradientBoosting) Fit(
 	// Required arguments
@@ -123,12 +217,48 @@ func (gb *GradientBoosting) Fit(
 	YVal []float64,
 	WVal []float64,
 	verbose bool,
+) error {
+	return gb.FitContext(context.Background(), X, Y, W, XVal, YVal, WVal, verbose)
+}
+
+// FitContext is the same as Fit except that training stops once ctx is done.
+// Cancellation is checked between boosting rounds and is also passed on to
+// the GP of each round. MaxDuration and MaxEvaluations apply to the whole
+// training and are handled in the same way. If ctx is done or if a budget is
+// exhausted then no error is returned and the rounds that have been completed
+// so far are kept. In case of multi-class classification a round is only
+// completed once the GP of every class has been trained.
+func (gb *GradientBoosting) FitContext(
+	ctx context.Context,
+	// Required arguments
+	X [][]float64,
+	Y []float64,
+	// Optional arguments (can safely be nil)
+	W []float64,
+	XVal [][]float64,
+	YVal []float64,
+	WVal []float64,
+	verbose bool,
 ) error {
 	var start = time.Now()
 
+	// Enforce the time budget through the context
+	if gb.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, gb.MaxDuration)
+		defer cancel()
+	}
+	gb.nEvaluations = 0
+
 	// We use symbolic regression even if the task is classication
 	if gb.Loss.Classification() {
 		gb.LossMetric = metrics.MSE{}
+		gb.Classes = distinct(Y)
+	}
+
+	// Multi-class classification is handled separately
+	if gb.multiClass() {
+		return gb.fitSoftmax(ctx, X, Y, W, XVal, YVal, verbose)
 	}
 
 	// Start from the target mean
@@ -145,6 +275,14 @@ func (gb *GradientBoosting) Fit(
 	)
 
 	for i := uint(0); i < gb.NRounds; i++ {
+		// Check for cancellation and for the budget of evaluations
+		if ctx.Err() != nil || gb.budgetExhausted() {
+			if verbose {
+				fmt.Println("Training interrupted")
+			}
+			break
+		}
+
 		// Compute the gradients
 		var (
 			grads []float64
@@ -177,14 +315,15 @@ func (gb *GradientBoosting) Fit(
 		}
 
 		// Train a GP on the negative gradients
-		gp, err := gb.NewGP()
+		gp, err := gb.newGP()
 		if err != nil {
 			return err
 		}
-		err = gp.Fit(features, grads, W, nil, nil, nil, false)
+		err = gp.FitContext(ctx, features, grads, W, nil, nil, nil, false)
 		if err != nil {
 			return err
 		}
+		gb.nEvaluations += gp.NEvaluations()
 
 		// Extract the best obtained Program
 		prog, err := gp.BestProgram()
@@ -290,23 +429,287 @@ func (gb *GradientBoosting) Fit(
 
 	// Only keep the best rounds
 	if gb.UseBestRounds {
-		var b = gb.bestRound() + 1
+		gb.keepBestRounds()
+	}
+
+	return nil
+}
+
+// keepBestRounds discards the rounds that come after the best round.
+func (gb *GradientBoosting) keepBestRounds() {
+	var b = gb.bestRound() + 1
+	if len(gb.Programs) > b {
 		gb.Programs = gb.Programs[:b]
 		gb.Steps = gb.Steps[:b]
-		if len(gb.UsedCols) > b {
-			gb.UsedCols = gb.UsedCols[:b]
+	}
+	if len(gb.ClassPrograms) > b {
+		gb.ClassPrograms = gb.ClassPrograms[:b]
+		gb.ClassSteps = gb.ClassSteps[:b]
+	}
+	if len(gb.UsedCols) > b {
+		gb.UsedCols = gb.UsedCols[:b]
+	}
+	if len(gb.ValScores) > b {
+		gb.ValScores = gb.ValScores[:b]
+	}
+	if len(gb.TrainScores) > b {
+		gb.TrainScores = gb.TrainScores[:b]
+	}
+}
+
+// fitSoftmax iteratively trains one GP per class on the gradient of the
+// multinomial log-loss.
+func (gb *GradientBoosting) fitSoftmax(
+	ctx context.Context,
+	X [][]float64,
+	Y []float64,
+	W []float64,
+	XVal [][]float64,
+	YVal []float64,
+	verbose bool,
+) error {
+	var start = time.Now()
+
+	// Probabilities are needed to compute the gradients of each class and the
+	// loss has to handle the probabilities of all the classes for the line
+	// search
+	loss, ok := gb.Loss.(metrics.ProbaMetric)
+	if !ok || !gb.Loss.NeedsProbabilities() {
+		return fmt.Errorf("The '%s' metric can't be used for multi-class gradient boosting",
+			gb.Loss.String())
+	}
+
+	// Start from the log of the class priors
+	var (
+		YBin = make([][]float64, len(gb.Classes))
+		F    = make([][]float64, len(gb.Classes))
+	)
+	gb.ClassPriors = make([]float64, len(gb.Classes))
+	for k, class := range gb.Classes {
+		YBin[k] = binarize(Y, class)
+		gb.ClassPriors[k] = mean(YBin[k])
+		F[k] = make([]float64, len(Y))
+		for i := range F[k] {
+			F[k][i] = math.Log(gb.ClassPriors[k])
+		}
+	}
+
+	// Store the best validation score in order to check for early stopping
+	var (
+		bestVal          = math.Inf(1)
+		earlyStopCounter = gb.NEarlyStoppingRounds
+	)
+
+	for i := uint(0); i < gb.NRounds; i++ {
+		// Check for cancellation and for the budget of evaluations
+		if ctx.Err() != nil || gb.budgetExhausted() {
+			if verbose {
+				fmt.Println("Training interrupted")
+			}
+			break
 		}
-		if len(gb.ValScores) > b {
-			gb.ValScores = gb.ValScores[:b]
+
+		var probas = softmax(F)
+
+		// Subsample, the same rows are used for training the GP of each
+		// class whereas the updates are computed on all the rows
+		var (
+			features = X
+			cols     []int
+		)
+		if gb.ColSampling < 1 {
+			p := uint(gb.ColSampling * float64(len(X)))
+			cols = randomInts(p, 0, len(X), gb.RNG)
+			features = selectCols(X, cols)
+		}
+		var (
+			rows  []int
+			XRows = features
+			WRows = W
+		)
+		if gb.RowSampling < 1 {
+			n := uint(gb.RowSampling * float64(len(Y)))
+			if n < 1 {
+				n = 1
+			}
+			rows = randomInts(n, 0, len(Y), gb.RNG)
+			XRows = make([][]float64, len(features))
+			for j, x := range features {
+				XRows[j] = take(x, rows)
+			}
+			WRows = take(W, rows)
+		}
+
+		var (
+			progs       = make([]xgp.Program, len(gb.Classes))
+			steps       = make([]float64, len(gb.Classes))
+			updates     = make([][]float64, len(gb.Classes))
+			interrupted bool
+		)
+		for k := range gb.Classes {
+			// Compute the gradients of the class
+			grads, err := gb.Loss.Gradients(YBin[k], probas[k])
+			if err != nil {
+				return err
+			}
+			if rows != nil {
+				grads = take(grads, rows)
+			}
+
+			// Train a GP on the negative gradients
+			gp, err := gb.newGP()
+			if err != nil {
+				return err
+			}
+			err = gp.FitContext(ctx, XRows, grads, WRows, nil, nil, nil, false)
+			if err != nil {
+				return err
+			}
+			gb.nEvaluations += gp.NEvaluations()
+
+			// The round is only kept if the GP of every class finished
+			// training
+			if ctx.Err() != nil || gb.budgetExhausted() {
+				interrupted = true
+				break
+			}
+
+			// Extract the best obtained Program
+			progs[k], err = gp.BestProgram()
+			if err != nil {
+				return err
+			}
+
+			// Make predictions
+			updates[k], err = progs[k].Predict(features, false)
+			if err != nil {
+				return err
+			}
+
+			// Find a good step size using line search on the multinomial
+			// loss, the other classes being left untouched
+			steps[k] = 1
+			if gb.LineSearcher != nil {
+				var (
+					FF = make([][]float64, len(F))
+					ff = make([]float64, len(F[k]))
+				)
+				copy(FF, F)
+				FF[k] = ff
+				steps[k] = gb.LineSearcher.Solve(
+					func(step float64) float64 {
+						for j, u := range updates[k] {
+							ff[j] = F[k][j] - gb.LearningRate*step*u
+						}
+						var l, _ = loss.ApplyProba(Y, softmax(FF), gb.Classes, nil)
+						return l
+					},
+				)
+			}
+		}
+		if interrupted {
+			if verbose {
+				fmt.Println("Training interrupted")
+			}
+			break
+		}
+		if cols != nil {
+			gb.UsedCols = append(gb.UsedCols, cols)
+		}
+		gb.ClassPrograms = append(gb.ClassPrograms, progs)
+		gb.ClassSteps = append(gb.ClassSteps, steps)
+
+		for k, update := range updates {
+			for j, u := range update {
+				F[k][j] -= gb.LearningRate * steps[k] * u
+			}
+		}
+
+		// Compute training score
+		trainScore, err := gb.scoreProba(gb.EvalMetric, Y, softmax(F))
+		if err != nil {
+			return err
+		}
+		gb.TrainScores = append(gb.TrainScores, trainScore)
+
+		// If there is no validation set then stop and display training progress
+		if XVal == nil || YVal == nil || gb.EvalMetric == nil {
+			if verbose && gb.shouldMonitor(i) {
+				fmt.Printf(
+					"%s -- train %s: %.5f -- round %d\n",
+					fmtDuration(time.Since(start)),
+					gb.EvalMetric.String(),
+					trainScore,
+					i+1,
+				)
+			}
+			continue
+		}
+
+		// Compute validation score
+		YValProbas, err := gb.PredictProba(XVal)
+		if err != nil {
+			return err
+		}
+		valScore, err := gb.scoreProba(gb.EvalMetric, YVal, YValProbas)
+		if err != nil {
+			return err
+		}
+		gb.ValScores = append(gb.ValScores, valScore)
+
+		// Display training and validation progress
+		if verbose && gb.shouldMonitor(i) {
+			fmt.Printf(
+				"%s -- train %s: %.5f -- val %s: %.5f -- round %d\n",
+				fmtDuration(time.Since(start)),
+				gb.EvalMetric.String(),
+				trainScore,
+				gb.EvalMetric.String(),
+				valScore,
+				i+1,
+			)
+		}
+
+		// Check for early stopping
+		if valScore < bestVal {
+			earlyStopCounter = gb.NEarlyStoppingRounds
+			bestVal = valScore
+		} else {
+			earlyStopCounter--
+		}
+		if earlyStopCounter == 0 {
+			if verbose {
+				fmt.Println("Early stopping")
+			}
+			break
 		}
-		gb.TrainScores = gb.TrainScores[:b]
+	}
+
+	// Only keep the best rounds
+	if gb.UseBestRounds {
+		gb.keepBestRounds()
 	}
 
 	return nil
 }
 
-// Predict accumulates the predictions of each stored Program.
+// Predict accumulates the predictions of each stored Program. In case of
+// multi-class classification the most probable class is returned and class
+// probabilities have to be obtained with the PredictProba method.
 func (gb GradientBoosting) Predict(X [][]float64, proba bool) ([]float64, error) {
+	if len(X) == 0 {
+		return nil, errors.New("X has no features")
+	}
+	if gb.multiClass() {
+		if proba {
+			return nil, errors.New("Class probabilities have to be obtained with PredictProba")
+		}
+		probas, err := gb.PredictProba(X)
+		if err != nil {
+			return nil, err
+		}
+		return mostProbable(probas, gb.Classes), nil
+	}
 	// Start from the target mean
 	var YPred = make([]float64, len(X[0]))
 	for i := range YPred {
@@ -338,19 +741,73 @@ func (gb GradientBoosting) Predict(X [][]float64, proba bool) ([]float64, error)
 	return YPred, nil
 }
 
+// PredictProba returns the probability of each class. The output contains
+// one slice per class, ordered in the same way as the Classes field.
+func (gb GradientBoosting) PredictProba(X [][]float64) ([][]float64, error) {
+	if !gb.Loss.Classification() {
+		return nil, errors.New("Probabilities can only be predicted in case of classification")
+	}
+	if len(X) == 0 {
+		return nil, errors.New("X has no features")
+	}
+	// Binary classification
+	if !gb.multiClass() {
+		p, err := gb.Predict(X, true)
+		if err != nil {
+			return nil, err
+		}
+		var q = make([]float64, len(p))
+		for i, pi := range p {
+			q[i] = 1 - pi
+		}
+		return [][]float64{q, p}, nil
+	}
+	// Start from the log of the class priors
+	var F = make([][]float64, len(gb.Classes))
+	for k, prior := range gb.ClassPriors {
+		F[k] = make([]float64, len(X[0]))
+		for j := range F[k] {
+			F[k][j] = math.Log(prior)
+		}
+	}
+	// Accumulate predictions
+	for i, progs := range gb.ClassPrograms {
+		var features [][]float64
+		if len(gb.UsedCols) > 0 {
+			features = selectCols(X, gb.UsedCols[i])
+		} else {
+			features = X
+		}
+		for k, prog := range progs {
+			update, err := prog.Predict(features, false)
+			if err != nil {
+				return nil, err
+			}
+			for j, u := range update {
+				F[k][j] -= gb.LearningRate * gb.ClassSteps[i][k] * u
+			}
+		}
+	}
+	return softmax(F), nil
+}
+
 type serialGradientBoosting struct {
-	NRounds              uint          `json:"n_rounds"`
-	NEarlyStoppingRounds uint          `json:"n_early_stopping_round"`
-	LearningRate         float64       `json:"learning_rate"`
-	Loss                 string        `json:"loss_metric"`
-	RowSampling          float64       `json:"row_sampling"`
-	ColSampling          float64       `json:"col_sampling"`
-	Programs             []xgp.Program `json:"programs"`
-	Steps                []float64     `json:"steps"`
-	UsedCols             [][]int       `json:"used_columns"`
-	ValScores            []float64     `json:"val_scores"`
-	TrainScores          []float64     `json:"train_scores"`
-	YMean                float64       `json:"y_mean"`
+	NRounds              uint            `json:"n_rounds"`
+	NEarlyStoppingRounds uint            `json:"n_early_stopping_round"`
+	LearningRate         float64         `json:"learning_rate"`
+	Loss                 string          `json:"loss_metric"`
+	RowSampling          float64         `json:"row_sampling"`
+	ColSampling          float64         `json:"col_sampling"`
+	Programs             []xgp.Program   `json:"programs"`
+	Steps                []float64       `json:"steps"`
+	UsedCols             [][]int         `json:"used_columns"`
+	ValScores            []float64       `json:"val_scores"`
+	TrainScores          []float64       `json:"train_scores"`
+	YMean                float64         `json:"y_mean"`
+	Classes              []float64       `json:"classes,omitempty"`
+	ClassPriors          []float64       `json:"class_priors,omitempty"`
+	ClassPrograms        [][]xgp.Program `json:"class_programs,omitempty"`
+	ClassSteps           [][]float64     `json:"class_steps,omitempty"`
 }
 
 // MarshalJSON serializes a GradientBoosting.
@@ -362,9 +819,14 @@ func (gb GradientBoosting) MarshalJSON() ([]byte, error) {
 		Loss:                 gb.Loss.String(),
 		Programs:             gb.Programs,
 		Steps:                gb.Steps,
+		UsedCols:             gb.UsedCols,
 		ValScores:            gb.ValScores,
 		TrainScores:          gb.TrainScores,
 		YMean:                gb.YMean,
+		Classes:              gb.Classes,
+		ClassPriors:          gb.ClassPriors,
+		ClassPrograms:        gb.ClassPrograms,
+		ClassSteps:           gb.ClassSteps,
 	})
 }
 
@@ -389,8 +851,13 @@ func (gb *GradientBoosting) UnmarshalJSON(bytes []byte) error {
 	gb.Loss = dloss
 	gb.Programs = serial.Programs
 	gb.Steps = serial.Steps
+	gb.UsedCols = serial.UsedCols
 	gb.ValScores = serial.ValScores
 	gb.TrainScores = serial.TrainScores
 	gb.YMean = serial.YMean
+	gb.Classes = serial.Classes
+	gb.ClassPriors = serial.ClassPriors
+	gb.ClassPrograms = serial.ClassPrograms
+	gb.ClassSteps = serial.ClassSteps
 	return nil
 }
@@ -0,0 +1,441 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MaxHalford/xgp"
+	"github.com/MaxHalford/xgp/metrics"
+	"github.com/MaxHalford/xgp/op"
+)
+
+func TestGradientBoostingMultiClass(t *testing.T) {
+	var conf = xgp.NewDefaultGPConfig()
+	conf.LossMetric = metrics.LogLoss{}
+	conf.EvalMetric = metrics.Accuracy{}
+	conf.NIndividuals = 20
+	conf.NGenerations = 5
+	conf.PolishBest = false
+	conf.RNG = rand.New(rand.NewSource(42))
+	gb, err := NewGradientBoosting(
+		conf,
+		3,
+		3,
+		0.1,
+		GoldenLineSearch{Min: 0, Max: 10, Tol: 1e-10},
+		metrics.LogLoss{},
+		1,
+		1,
+		false,
+		1,
+		conf.RNG,
+	)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if err = gb.Fit(XMulti, YMulti, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(gb.ClassPrograms) != 3 {
+		t.Errorf("Expected 3 rounds, got %d", len(gb.ClassPrograms))
+		return
+	}
+	for _, progs := range gb.ClassPrograms {
+		if len(progs) != 3 {
+			t.Errorf("Expected 3 programs, got %d", len(progs))
+		}
+	}
+	probas, err := gb.PredictProba(XMulti)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if _, err = gb.PredictProba([][]float64{}); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	if _, err = gb.Predict([][]float64{}, false); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	for j := range YMulti {
+		var sum float64
+		for k := range probas {
+			sum += probas[k][j]
+		}
+		if math.Abs(sum-1) > 10e-10 {
+			t.Errorf("Expected probabilities to sum to 1, got %.5f", sum)
+		}
+	}
+	// Check the serialized model makes the same predictions
+	bytes, err := json.Marshal(gb)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var newGB GradientBoosting
+	if err = json.Unmarshal(bytes, &newGB); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	newProbas, err := newGB.PredictProba(XMulti)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	for k := range probas {
+		for j := range probas[k] {
+			if probas[k][j] != newProbas[k][j] {
+				t.Errorf("Expected %.5f, got %.5f", probas[k][j], newProbas[k][j])
+			}
+		}
+	}
+}
+
+func TestGradientBoostingFitContext(t *testing.T) {
+	var conf = xgp.NewDefaultGPConfig()
+	conf.NIndividuals = 20
+	conf.NGenerations = 5
+	conf.PolishBest = false
+	conf.RNG = rand.New(rand.NewSource(42))
+	gb, err := NewGradientBoosting(
+		conf,
+		3,
+		3,
+		0.1,
+		nil,
+		metrics.MSE{},
+		1,
+		1,
+		true,
+		1,
+		conf.RNG,
+	)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var (
+		X = [][]float64{[]float64{1, 2, 3}}
+		Y = []float64{2, 4, 6}
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = gb.FitContext(ctx, X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(gb.Programs) != 0 {
+		t.Errorf("Expected 0 rounds, got %d", len(gb.Programs))
+	}
+	yPred, err := gb.Predict(X, false)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	for _, y := range yPred {
+		if y != 4 {
+			t.Errorf("Expected 4, got %.5f", y)
+		}
+	}
+	if _, err = gb.Predict([][]float64{}, false); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
+
+func TestGradientBoostingBudget(t *testing.T) {
+	var conf = xgp.NewDefaultGPConfig()
+	conf.NIndividuals = 20
+	conf.NGenerations = 5
+	conf.PolishBest = false
+	conf.EvalMetric = metrics.MSE{}
+	conf.MaxEvaluations = 100
+	conf.RNG = rand.New(rand.NewSource(42))
+	gb, err := NewGradientBoosting(
+		conf,
+		50,
+		3,
+		0.1,
+		nil,
+		metrics.MSE{},
+		1,
+		1,
+		false,
+		1,
+		conf.RNG,
+	)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var (
+		X = [][]float64{[]float64{1, 2, 3}}
+		Y = []float64{2, 4, 6}
+	)
+	if err = gb.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(gb.Programs) == 0 || len(gb.Programs) >= 50 {
+		t.Errorf("Expected between 1 and 49 rounds, got %d", len(gb.Programs))
+	}
+	if gb.NEvaluations() < 100 {
+		t.Errorf("Expected at least 100 evaluations, got %d", gb.NEvaluations())
+	}
+}
+
+func TestGradientBoostingLinearScaling(t *testing.T) {
+	var conf = xgp.NewDefaultGPConfig()
+	conf.NIndividuals = 20
+	conf.NGenerations = 5
+	conf.PolishBest = false
+	conf.EvalMetric = metrics.MSE{}
+	conf.LinearScaling = true
+	conf.RNG = rand.New(rand.NewSource(42))
+	gb, err := NewGradientBoosting(
+		conf,
+		3,
+		3,
+		0.5,
+		nil,
+		metrics.MSE{},
+		1,
+		1,
+		false,
+		1,
+		conf.RNG,
+	)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var (
+		X = [][]float64{[]float64{1, 2, 3}}
+		Y = []float64{20, 40, 60}
+	)
+	if err = gb.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	for i, prog := range gb.Programs {
+		if prog.Scaling == nil {
+			t.Errorf("Expected the Program of round %d to have a Scaling", i)
+		}
+	}
+	// The Scalings have to be serialized along with the Programs
+	bytes, err := json.Marshal(gb)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var newGB = &GradientBoosting{}
+	if err = json.Unmarshal(bytes, newGB); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	yPred, err := gb.Predict(X, false)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	newYPred, err := newGB.Predict(X, false)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	for i := range yPred {
+		if math.Abs(yPred[i]-newYPred[i]) > 10e-10 {
+			t.Errorf("Expected %.5f, got %.5f", yPred[i], newYPred[i])
+		}
+	}
+}
+
+func TestGradientBoostingMultiClassLogLoss(t *testing.T) {
+	var testCases = []struct {
+		rowSampling float64
+	}{
+		{rowSampling: 1},
+		{rowSampling: 0.5},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var conf = xgp.NewDefaultGPConfig()
+			conf.LossMetric = metrics.LogLoss{}
+			conf.EvalMetric = metrics.LogLoss{}
+			conf.NIndividuals = 20
+			conf.NGenerations = 5
+			conf.PolishBest = false
+			conf.RNG = rand.New(rand.NewSource(42))
+			gb, err := NewGradientBoosting(
+				conf,
+				3,
+				3,
+				0.1,
+				GoldenLineSearch{Min: 0, Max: 10, Tol: 1e-10},
+				metrics.LogLoss{},
+				tc.rowSampling,
+				1,
+				false,
+				1,
+				conf.RNG,
+			)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if err = gb.Fit(XMulti, YMulti, nil, nil, nil, nil, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			// The training scores are multinomial log-losses
+			probas, err := gb.PredictProba(XMulti)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			loss, err := metrics.LogLoss{}.ApplyProba(YMulti, probas, gb.Classes, nil)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			var trainScore = gb.TrainScores[len(gb.TrainScores)-1]
+			if math.Abs(trainScore-loss) > 10e-10 {
+				t.Errorf("Expected %.5f, got %.5f", loss, trainScore)
+			}
+			// The model should do better than the class priors, which are
+			// uniform
+			if trainScore > math.Log(3) {
+				t.Errorf("Expected a loss lower than %.5f, got %.5f", math.Log(3), trainScore)
+			}
+		})
+	}
+}
+
+func TestGradientBoostingMultiClassInterrupted(t *testing.T) {
+	var testCases = []struct {
+		cancel    bool
+		minRounds int
+	}{
+		{cancel: false, minRounds: 1},
+		{cancel: true, minRounds: 0},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var conf = xgp.NewDefaultGPConfig()
+			conf.LossMetric = metrics.LogLoss{}
+			conf.EvalMetric = metrics.LogLoss{}
+			conf.NIndividuals = 20
+			conf.NGenerations = 5
+			conf.PolishBest = false
+			conf.MaxEvaluations = 500
+			conf.RNG = rand.New(rand.NewSource(42))
+			gb, err := NewGradientBoosting(
+				conf,
+				50,
+				3,
+				0.1,
+				nil,
+				metrics.LogLoss{},
+				1,
+				0.5,
+				false,
+				1,
+				conf.RNG,
+			)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			var ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				cancel()
+			}
+			if err = gb.FitContext(ctx, XMulti, YMulti, nil, nil, nil, nil, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			// The budget runs out in the middle of a round, which is then
+			// dropped
+			var n = len(gb.ClassPrograms)
+			if n < tc.minRounds || n >= 50 {
+				t.Errorf("Expected between %d and 49 rounds, got %d", tc.minRounds, n)
+			}
+			if len(gb.ClassSteps) != n || len(gb.UsedCols) != n || len(gb.TrainScores) != n {
+				t.Errorf("Expected %d rounds, got %d steps, %d column samples and %d scores",
+					n, len(gb.ClassSteps), len(gb.UsedCols), len(gb.TrainScores))
+			}
+			for _, progs := range gb.ClassPrograms {
+				for _, prog := range progs {
+					if prog.Op == nil {
+						t.Error("Expected a trained Program, got nil")
+					}
+				}
+			}
+			if _, err = gb.PredictProba(XMulti); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+			}
+		})
+	}
+}
+
+func TestGradientBoostingCheckpointAndSeeds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xgp")
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	var (
+		path = filepath.Join(dir, "checkpoint.json")
+		conf = xgp.NewDefaultGPConfig()
+	)
+	conf.NIndividuals = 20
+	conf.NGenerations = 5
+	conf.PolishBest = false
+	conf.EvalMetric = metrics.MSE{}
+	conf.Seeds = []op.Operator{op.Var{Index: 0}}
+	conf.CheckpointPath = path
+	conf.CheckpointEvery = 1
+	conf.Resume = true
+	conf.RNG = rand.New(rand.NewSource(42))
+	gb, err := NewGradientBoosting(
+		conf,
+		3,
+		3,
+		0.1,
+		nil,
+		metrics.MSE{},
+		1,
+		1,
+		false,
+		1,
+		conf.RNG,
+	)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var (
+		X = [][]float64{[]float64{1, 2, 3}}
+		Y = []float64{2, 4, 6}
+	)
+	// The GP of each round neither resumes from nor writes a checkpoint
+	if err = gb.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no checkpoint, got %v", err)
+	}
+	if len(gb.Programs) != 3 {
+		t.Errorf("Expected 3 rounds, got %d", len(gb.Programs))
+	}
+}
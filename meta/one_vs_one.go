@@ -0,0 +1,235 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// OneVsOne handles multi-class classification by training one binary
+// Estimator per pair of classes. Each Estimator is trained on the rows that
+// belong to one of the two classes of it's pair and learns to distinguish the
+// second class from the first one.
+type OneVsOne struct {
+	NewEstimator func(rng *rand.Rand) (Estimator, error)
+	Classes      []float64
+	Predictors   []Predictor
+	RNG          *rand.Rand
+}
+
+// NewOneVsOne returns a OneVsOne. newEstimator is called once per pair of
+// classes with a dedicated random number generator.
+func NewOneVsOne(
+	newEstimator func(rng *rand.Rand) (Estimator, error),
+	rng *rand.Rand,
+) (*OneVsOne, error) {
+	if newEstimator == nil {
+		return nil, errors.New("newEstimator can't be nil")
+	}
+	if rng == nil {
+		return nil, errors.New("rng can't be nil")
+	}
+	return &OneVsOne{
+		NewEstimator: newEstimator,
+		Predictors:   make([]Predictor, 0),
+		RNG:          rng,
+	}, nil
+}
+
+// pairs returns the positions of the classes of each pair. The pairs are
+// ordered in the same way as the Predictors field.
+func (ovo OneVsOne) pairs() [][2]int {
+	var pairs = make([][2]int, 0)
+	for i := range ovo.Classes {
+		for j := i + 1; j < len(ovo.Classes); j++ {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	return pairs
+}
+
+// rowsOf returns the position of the rows that belong to one of two classes.
+func rowsOf(y []float64, a, b float64) []int {
+	var rows = make([]int, 0)
+	for i, yi := range y {
+		if yi == a || yi == b {
+			rows = append(rows, i)
+		}
+	}
+	return rows
+}
+
+// Fit trains one Estimator per pair of classes in parallel. An error is
+// returned if Y contains less than two classes.
+func (ovo *OneVsOne) Fit(
+	// Required arguments
+	X [][]float64,
+	Y []float64,
+	// Optional arguments (can safely be nil)
+	W []float64,
+	XVal [][]float64,
+	YVal []float64,
+	WVal []float64,
+	verbose bool,
+) error {
+	return ovo.FitContext(context.Background(), X, Y, W, XVal, YVal, WVal, verbose)
+}
+
+// FitContext is the same as Fit except that ctx is passed on to the
+// Estimators which are ContextEstimators.
+func (ovo *OneVsOne) FitContext(
+	ctx context.Context,
+	// Required arguments
+	X [][]float64,
+	Y []float64,
+	// Optional arguments (can safely be nil)
+	W []float64,
+	XVal [][]float64,
+	YVal []float64,
+	WVal []float64,
+	verbose bool,
+) error {
+	var start = time.Now()
+	ovo.Classes = distinct(Y)
+	if len(ovo.Classes) < 2 {
+		return errors.New("Y has to contain at least two classes")
+	}
+	var pairs = ovo.pairs()
+
+	// Instantiate the Estimators sequentially so that the random number
+	// generators don't depend on the order in which the goroutines are run
+	var estimators = make([]Estimator, len(pairs))
+	for i := range estimators {
+		est, err := ovo.NewEstimator(rand.New(rand.NewSource(ovo.RNG.Int63())))
+		if err != nil {
+			return err
+		}
+		estimators[i] = est
+	}
+
+	// Train each Estimator on the rows of it's pair of classes
+	ovo.Predictors = make([]Predictor, len(pairs))
+	return fitParallel(len(pairs), func(i int) error {
+		var (
+			a     = ovo.Classes[pairs[i][0]]
+			b     = ovo.Classes[pairs[i][1]]
+			rows  = rowsOf(Y, a, b)
+			XX    = takeRows(X, rows)
+			YY    = binarize(take(Y, rows), b)
+			WW    = take(W, rows)
+			XXVal [][]float64
+			YYVal []float64
+			WWVal []float64
+		)
+		if XVal != nil && YVal != nil {
+			var valRows = rowsOf(YVal, a, b)
+			XXVal = takeRows(XVal, valRows)
+			YYVal = binarize(take(YVal, valRows), b)
+			WWVal = take(WVal, valRows)
+		}
+		err := fitContext(ctx, estimators[i], XX, YY, WW, XXVal, YYVal, WWVal, false)
+		if err != nil {
+			return err
+		}
+		pred, err := extractPredictor(estimators[i])
+		if err != nil {
+			return err
+		}
+		ovo.Predictors[i] = pred
+		if verbose {
+			fmt.Printf(
+				"%s -- class %s vs class %s done\n",
+				fmtDuration(time.Since(start)),
+				strconv.FormatFloat(a, 'f', -1, 64),
+				strconv.FormatFloat(b, 'f', -1, 64),
+			)
+		}
+		return nil
+	})
+}
+
+// PredictProba returns the probability of each class. The output contains
+// one slice per class, ordered in the same way as the Classes field. The
+// probability of a class is the sum of the probabilities it obtains against
+// each other class, divided by the number of pairs of classes.
+func (ovo OneVsOne) PredictProba(X [][]float64) ([][]float64, error) {
+	if len(ovo.Predictors) == 0 {
+		return nil, errors.New("The OneVsOne has not been trained yet")
+	}
+	if len(X) == 0 {
+		return nil, errors.New("X has no features")
+	}
+	var probas = make([][]float64, len(ovo.Classes))
+	for i := range probas {
+		probas[i] = make([]float64, len(X[0]))
+	}
+	var pairs = ovo.pairs()
+	for i, pred := range ovo.Predictors {
+		p, err := pred.Predict(X, true)
+		if err != nil {
+			return nil, err
+		}
+		for j, pj := range p {
+			probas[pairs[i][0]][j] += 1 - pj
+			probas[pairs[i][1]][j] += pj
+		}
+	}
+	for i := range probas {
+		for j := range probas[i] {
+			probas[i][j] /= float64(len(pairs))
+		}
+	}
+	return probas, nil
+}
+
+// Predict returns the most probable class for each row in X. Class
+// probabilities have to be obtained with the PredictProba method.
+func (ovo OneVsOne) Predict(X [][]float64, proba bool) ([]float64, error) {
+	if proba {
+		return nil, errors.New("Class probabilities have to be obtained with PredictProba")
+	}
+	probas, err := ovo.PredictProba(X)
+	if err != nil {
+		return nil, err
+	}
+	return mostProbable(probas, ovo.Classes), nil
+}
+
+type serialOneVsOne struct {
+	Classes    []float64         `json:"classes"`
+	Predictors []serialPredictor `json:"predictors"`
+}
+
+// MarshalJSON serializes a OneVsOne.
+func (ovo OneVsOne) MarshalJSON() ([]byte, error) {
+	preds, err := serializePredictors(ovo.Predictors)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&serialOneVsOne{
+		Classes:    ovo.Classes,
+		Predictors: preds,
+	})
+}
+
+// UnmarshalJSON parses a OneVsOne.
+func (ovo *OneVsOne) UnmarshalJSON(bytes []byte) error {
+	var serial = &serialOneVsOne{}
+	if err := json.Unmarshal(bytes, serial); err != nil {
+		return err
+	}
+	ovo.Classes = serial.Classes
+	if len(serial.Predictors) != len(ovo.pairs()) {
+		return errors.New("The number of predictors doesn't match the number of pairs of classes")
+	}
+	preds, err := parsePredictors(serial.Predictors)
+	if err != nil {
+		return err
+	}
+	ovo.Predictors = preds
+	return nil
+}
@@ -0,0 +1,78 @@
+package meta
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestOneVsOne(t *testing.T) {
+	ovo, err := NewOneVsOne(newTestGP, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if err = ovo.Fit(XMulti, YMulti, nil, XMulti, YMulti, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(ovo.Predictors) != 3 {
+		t.Errorf("Expected 3 predictors, got %d", len(ovo.Predictors))
+		return
+	}
+	probas, err := ovo.PredictProba(XMulti)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if _, err = ovo.PredictProba([][]float64{}); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	for j := range YMulti {
+		var sum float64
+		for i := range probas {
+			sum += probas[i][j]
+		}
+		if math.Abs(sum-1) > 10e-10 {
+			t.Errorf("Expected probabilities to sum to 1, got %.5f", sum)
+		}
+	}
+	// Check the serialized model makes the same predictions
+	bytes, err := json.Marshal(ovo)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var newOVO OneVsOne
+	if err = json.Unmarshal(bytes, &newOVO); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	yPred, _ := ovo.Predict(XMulti, false)
+	newYPred, err := newOVO.Predict(XMulti, false)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	for i := range yPred {
+		if yPred[i] != newYPred[i] {
+			t.Errorf("Expected %.5f, got %.5f", yPred[i], newYPred[i])
+		}
+	}
+}
+
+func TestOneVsOneErrors(t *testing.T) {
+	if _, err := NewOneVsOne(newTestGP, nil); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	ovo, err := NewOneVsOne(newTestGP, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	// A single class can't be distinguished from any other
+	if err = ovo.Fit(XMulti, []float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, nil, nil, nil, nil, false); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
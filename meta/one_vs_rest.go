@@ -0,0 +1,193 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// OneVsRest handles multi-class classification by training one binary
+// Estimator per class. Each Estimator learns to distinguish it's class from
+// the rest of the classes.
+type OneVsRest struct {
+	NewEstimator func(rng *rand.Rand) (Estimator, error)
+	Classes      []float64
+	Predictors   []Predictor
+	RNG          *rand.Rand
+}
+
+// NewOneVsRest returns a OneVsRest. newEstimator is called once per class
+// with a dedicated random number generator.
+func NewOneVsRest(
+	newEstimator func(rng *rand.Rand) (Estimator, error),
+	rng *rand.Rand,
+) (*OneVsRest, error) {
+	if newEstimator == nil {
+		return nil, errors.New("newEstimator can't be nil")
+	}
+	if rng == nil {
+		return nil, errors.New("rng can't be nil")
+	}
+	return &OneVsRest{
+		NewEstimator: newEstimator,
+		Predictors:   make([]Predictor, 0),
+		RNG:          rng,
+	}, nil
+}
+
+// Fit trains one Estimator per class in parallel. An error is returned if Y
+// contains less than two classes.
+func (ovr *OneVsRest) Fit(
+	// Required arguments
+	X [][]float64,
+	Y []float64,
+	// Optional arguments (can safely be nil)
+	W []float64,
+	XVal [][]float64,
+	YVal []float64,
+	WVal []float64,
+	verbose bool,
+) error {
+	return ovr.FitContext(context.Background(), X, Y, W, XVal, YVal, WVal, verbose)
+}
+
+// FitContext is the same as Fit except that ctx is passed on to the
+// Estimators which are ContextEstimators.
+func (ovr *OneVsRest) FitContext(
+	ctx context.Context,
+	// Required arguments
+	X [][]float64,
+	Y []float64,
+	// Optional arguments (can safely be nil)
+	W []float64,
+	XVal [][]float64,
+	YVal []float64,
+	WVal []float64,
+	verbose bool,
+) error {
+	var start = time.Now()
+	ovr.Classes = distinct(Y)
+	if len(ovr.Classes) < 2 {
+		return errors.New("Y has to contain at least two classes")
+	}
+
+	// Instantiate the Estimators sequentially so that the random number
+	// generators don't depend on the order in which the goroutines are run
+	var estimators = make([]Estimator, len(ovr.Classes))
+	for i := range estimators {
+		est, err := ovr.NewEstimator(rand.New(rand.NewSource(ovr.RNG.Int63())))
+		if err != nil {
+			return err
+		}
+		estimators[i] = est
+	}
+
+	// Train each Estimator on it's own binary problem
+	ovr.Predictors = make([]Predictor, len(ovr.Classes))
+	return fitParallel(len(ovr.Classes), func(i int) error {
+		var class = ovr.Classes[i]
+		err := fitContext(ctx, estimators[i], X, binarize(Y, class), W, XVal, binarize(YVal, class), WVal, false)
+		if err != nil {
+			return err
+		}
+		pred, err := extractPredictor(estimators[i])
+		if err != nil {
+			return err
+		}
+		ovr.Predictors[i] = pred
+		if verbose {
+			fmt.Printf(
+				"%s -- class %s vs rest done\n",
+				fmtDuration(time.Since(start)),
+				strconv.FormatFloat(class, 'f', -1, 64),
+			)
+		}
+		return nil
+	})
+}
+
+// PredictProba returns the probability of each class. The output contains
+// one slice per class, ordered in the same way as the Classes field. The
+// probabilities of the Predictors are normalized so that they sum up to 1.
+func (ovr OneVsRest) PredictProba(X [][]float64) ([][]float64, error) {
+	if len(ovr.Predictors) == 0 {
+		return nil, errors.New("The OneVsRest has not been trained yet")
+	}
+	if len(X) == 0 {
+		return nil, errors.New("X has no features")
+	}
+	var probas = make([][]float64, len(ovr.Predictors))
+	for i, pred := range ovr.Predictors {
+		p, err := pred.Predict(X, true)
+		if err != nil {
+			return nil, err
+		}
+		probas[i] = p
+	}
+	for j := range probas[0] {
+		var sum float64
+		for i := range probas {
+			sum += probas[i][j]
+		}
+		for i := range probas {
+			if sum == 0 {
+				probas[i][j] = 1 / float64(len(probas))
+			} else {
+				probas[i][j] /= sum
+			}
+		}
+	}
+	return probas, nil
+}
+
+// Predict returns the most probable class for each row in X. Class
+// probabilities have to be obtained with the PredictProba method.
+func (ovr OneVsRest) Predict(X [][]float64, proba bool) ([]float64, error) {
+	if proba {
+		return nil, errors.New("Class probabilities have to be obtained with PredictProba")
+	}
+	probas, err := ovr.PredictProba(X)
+	if err != nil {
+		return nil, err
+	}
+	return mostProbable(probas, ovr.Classes), nil
+}
+
+type serialOneVsRest struct {
+	Classes    []float64         `json:"classes"`
+	Predictors []serialPredictor `json:"predictors"`
+}
+
+// MarshalJSON serializes a OneVsRest.
+func (ovr OneVsRest) MarshalJSON() ([]byte, error) {
+	preds, err := serializePredictors(ovr.Predictors)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&serialOneVsRest{
+		Classes:    ovr.Classes,
+		Predictors: preds,
+	})
+}
+
+// UnmarshalJSON parses a OneVsRest.
+func (ovr *OneVsRest) UnmarshalJSON(bytes []byte) error {
+	var serial = &serialOneVsRest{}
+	if err := json.Unmarshal(bytes, serial); err != nil {
+		return err
+	}
+	if len(serial.Predictors) != len(serial.Classes) {
+		return errors.New("The number of predictors doesn't match the number of classes")
+	}
+	preds, err := parsePredictors(serial.Predictors)
+	if err != nil {
+		return err
+	}
+	ovr.Classes = serial.Classes
+	ovr.Predictors = preds
+	return nil
+}
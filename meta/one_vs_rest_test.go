@@ -0,0 +1,103 @@
+package meta
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/MaxHalford/xgp"
+	"github.com/MaxHalford/xgp/metrics"
+)
+
+var (
+	XMulti = [][]float64{
+		[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		[]float64{9, 8, 7, 6, 5, 4, 3, 2, 1},
+	}
+	YMulti = []float64{0, 0, 0, 1, 1, 1, 2, 2, 2}
+)
+
+func newTestGP(rng *rand.Rand) (Estimator, error) {
+	var conf = xgp.NewDefaultGPConfig()
+	conf.LossMetric = metrics.LogLoss{}
+	conf.NIndividuals = 20
+	conf.NGenerations = 5
+	conf.PolishBest = false
+	conf.RNG = rng
+	gp, err := conf.NewGP()
+	if err != nil {
+		return nil, err
+	}
+	return gp, nil
+}
+
+func TestOneVsRest(t *testing.T) {
+	ovr, err := NewOneVsRest(newTestGP, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if err = ovr.Fit(XMulti, YMulti, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(ovr.Predictors) != 3 {
+		t.Errorf("Expected 3 predictors, got %d", len(ovr.Predictors))
+		return
+	}
+	probas, err := ovr.PredictProba(XMulti)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if _, err = ovr.PredictProba([][]float64{}); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	for j := range YMulti {
+		var sum float64
+		for i := range probas {
+			sum += probas[i][j]
+		}
+		if math.Abs(sum-1) > 10e-10 {
+			t.Errorf("Expected probabilities to sum to 1, got %.5f", sum)
+		}
+	}
+	// Check the serialized model makes the same predictions
+	bytes, err := json.Marshal(ovr)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var newOVR OneVsRest
+	if err = json.Unmarshal(bytes, &newOVR); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	yPred, _ := ovr.Predict(XMulti, false)
+	newYPred, err := newOVR.Predict(XMulti, false)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	for i := range yPred {
+		if yPred[i] != newYPred[i] {
+			t.Errorf("Expected %.5f, got %.5f", yPred[i], newYPred[i])
+		}
+	}
+}
+
+func TestOneVsRestErrors(t *testing.T) {
+	if _, err := NewOneVsRest(newTestGP, nil); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	ovr, err := NewOneVsRest(newTestGP, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	// A single class can't be distinguished from any other
+	if err = ovr.Fit(XMulti, []float64{1, 1, 1, 1, 1, 1, 1, 1, 1}, nil, nil, nil, nil, false); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
@@ -3,6 +3,7 @@ package meta
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"time"
 )
 
@@ -59,3 +60,76 @@ func selectRows(X [][]float64, rows []int) [][]float64 {
 	}
 	return XX
 }
+
+// take returns the values of a slice at the given positions. nil is returned
+// if the slice is nil.
+func take(x []float64, idx []int) []float64 {
+	if x == nil {
+		return nil
+	}
+	var xx = make([]float64, len(idx))
+	for i, j := range idx {
+		xx[i] = x[j]
+	}
+	return xx
+}
+
+// takeRows returns the given rows of a column-major matrix. nil is returned if
+// the matrix is nil.
+func takeRows(X [][]float64, rows []int) [][]float64 {
+	if X == nil {
+		return nil
+	}
+	var XX = make([][]float64, len(X))
+	for i, x := range X {
+		XX[i] = take(x, rows)
+	}
+	return XX
+}
+
+// distinct returns the distinct values of a slice in increasing order.
+func distinct(x []float64) []float64 {
+	var (
+		seen   = make(map[float64]bool)
+		values = make([]float64, 0)
+	)
+	for _, xi := range x {
+		if !seen[xi] {
+			seen[xi] = true
+			values = append(values, xi)
+		}
+	}
+	sort.Float64s(values)
+	return values
+}
+
+// binarize returns a slice where the values equal to the positive class are
+// replaced by 1 and the rest by 0. nil is returned if the slice is nil.
+func binarize(y []float64, positive float64) []float64 {
+	if y == nil {
+		return nil
+	}
+	var yy = make([]float64, len(y))
+	for i, yi := range y {
+		if yi == positive {
+			yy[i] = 1
+		}
+	}
+	return yy
+}
+
+// mostProbable returns the class with the highest probability for each row,
+// given one slice of probabilities per class.
+func mostProbable(probas [][]float64, classes []float64) []float64 {
+	var y = make([]float64, len(probas[0]))
+	for j := range y {
+		var best int
+		for i := range probas {
+			if probas[i][j] > probas[best][j] {
+				best = i
+			}
+		}
+		y[j] = classes[best]
+	}
+	return y
+}
@@ -52,3 +52,17 @@ func (acc Accuracy) NeedsProbabilities() bool {
 func (acc Accuracy) String() string {
 	return "accuracy"
 }
+
+// Residuals computes 1 if yPred[i] != yTrue[i] and 0 otherwise.
+func (acc Accuracy) Residuals(yTrue, yPred []float64) ([]float64, error) {
+	if len(yTrue) != len(yPred) {
+		return nil, &errMismatchedLengths{len(yTrue), len(yPred)}
+	}
+	var res = make([]float64, len(yTrue))
+	for i, y := range yTrue {
+		if yPred[i] != y {
+			res[i] = 1
+		}
+	}
+	return res, nil
+}
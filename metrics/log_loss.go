@@ -63,3 +63,67 @@ func (ll LogLoss) Gradients(yTrue, yPred []float64) ([]float64, error) {
 	}
 	return grad, nil
 }
+
+// Residuals computes the logistic loss of each sample.
+func (ll LogLoss) Residuals(yTrue, yPred []float64) ([]float64, error) {
+	if len(yTrue) != len(yPred) {
+		return nil, &errMismatchedLengths{len(yTrue), len(yPred)}
+	}
+	var res = make([]float64, len(yTrue))
+	for i, y := range yTrue {
+		var yp = clip(yPred[i], 0.00001, 0.99999)
+		res[i] = -(y*math.Log(yp) + (1-y)*math.Log(1-yp))
+	}
+	return res, nil
+}
+
+// ApplyProba computes the multinomial logistic loss, which is the average
+// negative log-probability of the true classes.
+func (ll LogLoss) ApplyProba(yTrue []float64, probas [][]float64, classes, weights []float64) (float64, error) {
+	if weights != nil && len(yTrue) != len(weights) {
+		return math.Inf(1), &errMismatchedLengths{len(yTrue), len(weights)}
+	}
+	res, err := ll.ResidualsProba(yTrue, probas, classes)
+	if err != nil {
+		return math.Inf(1), err
+	}
+
+	var score float64
+	if weights != nil {
+		var ws float64
+		for i, r := range res {
+			score += weights[i] * r
+			ws += weights[i]
+		}
+		return score / ws, nil
+	}
+
+	for _, r := range res {
+		score += r
+	}
+	return score / float64(len(yTrue)), nil
+}
+
+// ResidualsProba computes the negative log-probability of the true class of
+// each sample. The probability of a class which isn't part of classes is 0.
+func (ll LogLoss) ResidualsProba(yTrue []float64, probas [][]float64, classes []float64) ([]float64, error) {
+	if len(classes) != len(probas) {
+		return nil, &errMismatchedLengths{len(classes), len(probas)}
+	}
+	var indexes = make(map[float64]int, len(classes))
+	for k, class := range classes {
+		if len(probas[k]) != len(yTrue) {
+			return nil, &errMismatchedLengths{len(yTrue), len(probas[k])}
+		}
+		indexes[class] = k
+	}
+	var res = make([]float64, len(yTrue))
+	for i, y := range yTrue {
+		var yp float64
+		if k, ok := indexes[y]; ok {
+			yp = probas[k][i]
+		}
+		res[i] = -math.Log(clip(yp, 0.00001, 0.99999))
+	}
+	return res, nil
+}
@@ -2,6 +2,8 @@ package metrics
 
 import (
 	"fmt"
+	"math"
+	"reflect"
 	"testing"
 )
 
@@ -38,3 +40,55 @@ func TestLogLoss(t *testing.T) {
 		})
 	}
 }
+
+func TestLogLossProba(t *testing.T) {
+	var testCases = []struct {
+		yTrue   []float64
+		probas  [][]float64
+		classes []float64
+		weights []float64
+		score   float64
+		err     error
+	}{
+		{
+			yTrue:   []float64{0, 2},
+			probas:  [][]float64{{0.5, 0.2}, {0.3, 0.3}, {0.2, 0.5}},
+			classes: []float64{0, 1, 2},
+			weights: nil,
+			score:   0.69315,
+			err:     nil,
+		},
+		{
+			yTrue:   []float64{0, 2},
+			probas:  [][]float64{{0.9, 0.2}, {0.1, 0.3}, {0, 0.5}},
+			classes: []float64{0, 1, 2},
+			weights: []float64{1, 3},
+			score:   0.54620, // (0.10536 + 3 * 0.69315) / 4
+			err:     nil,
+		},
+		{
+			yTrue:   []float64{3},
+			probas:  [][]float64{{0.5}, {0.5}},
+			classes: []float64{1, 2},
+			weights: nil,
+			score:   11.51293, // The unknown class has a probability of 0
+			err:     nil,
+		},
+		{
+			yTrue:   []float64{0, 1},
+			probas:  [][]float64{{0.5}, {0.5}},
+			classes: []float64{0, 1},
+			weights: nil,
+			score:   math.Inf(1),
+			err:     &errMismatchedLengths{2, 1},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var score, err = LogLoss{}.ApplyProba(tc.yTrue, tc.probas, tc.classes, tc.weights)
+			if fmtScore(score) != fmtScore(tc.score) || !reflect.DeepEqual(err, tc.err) {
+				t.Errorf("Expected %s, got %s", fmtScore(tc.score), fmtScore(score))
+			}
+		})
+	}
+}
@@ -61,3 +61,39 @@ func (mae MAE) Gradients(yTrue, yPred []float64) ([]float64, error) {
 	}
 	return grad, nil
 }
+
+// Residuals computes |yPred[i] - yTrue[i]|.
+func (mae MAE) Residuals(yTrue, yPred []float64) ([]float64, error) {
+	if len(yTrue) != len(yPred) {
+		return nil, &errMismatchedLengths{len(yTrue), len(yPred)}
+	}
+	var res = make([]float64, len(yTrue))
+	for i, y := range yTrue {
+		res[i] = math.Abs(yPred[i] - y)
+	}
+	return res, nil
+}
+
+// Apply32 is the single precision version of Apply.
+func (mae MAE) Apply32(yTrue, yPred, weights []float32) (float64, error) {
+	if len(yTrue) != len(yPred) {
+		return math.Inf(1), &errMismatchedLengths{len(yTrue), len(yPred)}
+	}
+	if weights != nil && len(yTrue) != len(weights) {
+		return math.Inf(1), &errMismatchedLengths{len(yTrue), len(weights)}
+	}
+
+	var sum float64
+	if weights != nil {
+		var ws float64
+		for i := range yTrue {
+			sum += math.Abs(float64(yTrue[i]-yPred[i])) * float64(weights[i])
+			ws += float64(weights[i])
+		}
+		return sum / ws, nil
+	}
+	for i := range yTrue {
+		sum += math.Abs(float64(yTrue[i] - yPred[i]))
+	}
+	return sum / float64(len(yTrue)), nil
+}
@@ -16,3 +16,30 @@ type DiffMetric interface {
 	Metric
 	Gradients(yTrue, yPred []float64) ([]float64, error)
 }
+
+// A Float32Metric is a Metric that can be applied to single precision
+// values. The errors are computed with single precision but are summed with
+// double precision so that the precision of the score doesn't degrade with the
+// number of samples.
+type Float32Metric interface {
+	Metric
+	Apply32(yTrue, yPred, weights []float32) (float64, error)
+}
+
+// A ResidualMetric is a Metric that can compute the error made on each
+// sample. Lower errors are always better, even if the Metric's BiggerIsBetter
+// method returns true.
+type ResidualMetric interface {
+	Metric
+	Residuals(yTrue, yPred []float64) ([]float64, error)
+}
+
+// A ProbaMetric is a Metric that can be applied to the probabilities of each
+// class in case of multi-class classification. probas contains one slice per
+// class, ordered in the same way as classes. ResidualsProba computes the error
+// made on each sample, like the Residuals method of a ResidualMetric.
+type ProbaMetric interface {
+	Metric
+	ApplyProba(yTrue []float64, probas [][]float64, classes, weights []float64) (float64, error)
+	ResidualsProba(yTrue []float64, probas [][]float64, classes []float64) ([]float64, error)
+}
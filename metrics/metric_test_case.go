@@ -24,4 +24,22 @@ func (tc metricTestCase) Run(t *testing.T) {
 	if fmtScore(score) != fmtScore(tc.score) || !reflect.DeepEqual(err, tc.err) {
 		t.Errorf("Expected %s, got %s", fmtScore(tc.score), fmtScore(score))
 	}
+	// Check the single precision version gives the same score
+	if metric, ok := tc.metric.(Float32Metric); ok {
+		score, err = metric.Apply32(toFloat32(tc.yTrue), toFloat32(tc.yPred), toFloat32(tc.weights))
+		if fmtScore(score) != fmtScore(tc.score) || !reflect.DeepEqual(err, tc.err) {
+			t.Errorf("Expected %s, got %s with single precision", fmtScore(tc.score), fmtScore(score))
+		}
+	}
+}
+
+func toFloat32(x []float64) []float32 {
+	if x == nil {
+		return nil
+	}
+	var x32 = make([]float32, len(x))
+	for i, xi := range x {
+		x32[i] = float32(xi)
+	}
+	return x32
 }
@@ -58,3 +58,41 @@ func (mse MSE) Gradients(yTrue, yPred []float64) ([]float64, error) {
 	}
 	return grad, nil
 }
+
+// Residuals computes (yPred[i] - yTrue[i])².
+func (mse MSE) Residuals(yTrue, yPred []float64) ([]float64, error) {
+	if len(yTrue) != len(yPred) {
+		return nil, &errMismatchedLengths{len(yTrue), len(yPred)}
+	}
+	var res = make([]float64, len(yTrue))
+	for i, y := range yTrue {
+		res[i] = math.Pow(yPred[i]-y, 2)
+	}
+	return res, nil
+}
+
+// Apply32 is the single precision version of Apply.
+func (mse MSE) Apply32(yTrue, yPred, weights []float32) (float64, error) {
+	if len(yTrue) != len(yPred) {
+		return math.Inf(1), &errMismatchedLengths{len(yTrue), len(yPred)}
+	}
+	if weights != nil && len(yTrue) != len(weights) {
+		return math.Inf(1), &errMismatchedLengths{len(yTrue), len(weights)}
+	}
+
+	var sum float64
+	if weights != nil {
+		var ws float64
+		for i := range yTrue {
+			var d = yTrue[i] - yPred[i]
+			sum += float64(d*d) * float64(weights[i])
+			ws += float64(weights[i])
+		}
+		return sum / ws, nil
+	}
+	for i := range yTrue {
+		var d = yTrue[i] - yPred[i]
+		sum += float64(d * d)
+	}
+	return sum / float64(len(yTrue)), nil
+}
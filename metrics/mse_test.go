@@ -15,6 +15,14 @@ func TestMSE(t *testing.T) {
 			score:   0.375,
 			err:     nil,
 		},
+		{
+			yTrue:   []float64{3, -0.5, 2, 7},
+			yPred:   []float64{2.5, 0, 2, 8},
+			weights: []float64{1, 1, 2, 0},
+			metric:  MSE{},
+			score:   0.125,
+			err:     nil,
+		},
 	}
 	for i, tc := range testCases {
 		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
@@ -35,3 +35,17 @@ func (neg Negative) NeedsProbabilities() bool {
 func (neg Negative) String() string {
 	return fmt.Sprintf("neg_%s", neg.Metric.String())
 }
+
+// Apply32 is the single precision version of Apply. An error is returned if
+// the wrapped Metric isn't a Float32Metric.
+func (neg Negative) Apply32(yTrue, yPred, weights []float32) (float64, error) {
+	var metric, ok = neg.Metric.(Float32Metric)
+	if !ok {
+		return 0, fmt.Errorf("The '%s' metric can't be applied with single precision", neg.Metric.String())
+	}
+	var output, err = metric.Apply32(yTrue, yPred, weights)
+	if err != nil {
+		return 0, err
+	}
+	return -output, nil
+}
@@ -55,7 +55,7 @@ func (r2 R2) Classification() bool {
 
 // BiggerIsBetter method of R2.
 func (r2 R2) BiggerIsBetter() bool {
-	return false
+	return true
 }
 
 // NeedsProbabilities method of R2.
@@ -67,3 +67,55 @@ func (r2 R2) NeedsProbabilities() bool {
 func (r2 R2) String() string {
 	return "r2"
 }
+
+// Residuals computes (yPred[i] - yTrue[i])².
+func (r2 R2) Residuals(yTrue, yPred []float64) ([]float64, error) {
+	if len(yTrue) != len(yPred) {
+		return nil, &errMismatchedLengths{len(yTrue), len(yPred)}
+	}
+	var res = make([]float64, len(yTrue))
+	for i, y := range yTrue {
+		res[i] = math.Pow(yPred[i]-y, 2)
+	}
+	return res, nil
+}
+
+// Apply32 is the single precision version of Apply.
+func (r2 R2) Apply32(yTrue, yPred, weights []float32) (float64, error) {
+	if len(yTrue) != len(yPred) {
+		return math.Inf(1), &errMismatchedLengths{len(yTrue), len(yPred)}
+	}
+	if weights != nil && len(yTrue) != len(weights) {
+		return math.Inf(1), &errMismatchedLengths{len(yTrue), len(weights)}
+	}
+
+	// Compute the mean of the observed data
+	var yMean, ws float64
+	for i, y := range yTrue {
+		var w = 1.0
+		if weights != nil {
+			w = float64(weights[i])
+		}
+		yMean += float64(y) * w
+		ws += w
+	}
+	yMean /= ws
+
+	var (
+		SSR float64
+		SST float64
+	)
+	for i, y := range yTrue {
+		var (
+			w = 1.0
+			d = float64(yPred[i] - y)
+			e = float64(y) - yMean
+		)
+		if weights != nil {
+			w = float64(weights[i])
+		}
+		SSR += d * d * w
+		SST += e * e * w
+	}
+	return 1 - SSR/SST, nil
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestResiduals(t *testing.T) {
+	var testCases = []struct {
+		yTrue     []float64
+		yPred     []float64
+		metric    ResidualMetric
+		residuals []float64
+	}{
+		{
+			yTrue:     []float64{3, -0.5, 2, 7},
+			yPred:     []float64{2.5, 0, 2, 8},
+			metric:    MSE{},
+			residuals: []float64{0.25, 0.25, 0, 1},
+		},
+		{
+			yTrue:     []float64{3, -0.5, 2, 7},
+			yPred:     []float64{2.5, 0, 2, 8},
+			metric:    RMSE{},
+			residuals: []float64{0.25, 0.25, 0, 1},
+		},
+		{
+			yTrue:     []float64{3, -0.5, 2, 7},
+			yPred:     []float64{2.5, 0, 2, 8},
+			metric:    R2{},
+			residuals: []float64{0.25, 0.25, 0, 1},
+		},
+		{
+			yTrue:     []float64{3, -0.5, 2, 7},
+			yPred:     []float64{2.5, 0, 2, 8},
+			metric:    MAE{},
+			residuals: []float64{0.5, 0.5, 0, 1},
+		},
+		{
+			yTrue:     []float64{0, 1, 1},
+			yPred:     []float64{0, 1, 0},
+			metric:    Accuracy{},
+			residuals: []float64{0, 0, 1},
+		},
+		{
+			yTrue:     []float64{0, 1},
+			yPred:     []float64{0.5, 0.5},
+			metric:    LogLoss{},
+			residuals: []float64{0.69315, 0.69315},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var residuals, err = tc.metric.Residuals(tc.yTrue, tc.yPred)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			for j := range residuals {
+				if fmtScore(residuals[j]) != fmtScore(tc.residuals[j]) {
+					t.Errorf("Expected %s, got %s", fmtScore(tc.residuals[j]), fmtScore(residuals[j]))
+				}
+			}
+		})
+	}
+	if _, err := (MSE{}).Residuals([]float64{1}, []float64{1, 2}); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
@@ -33,3 +33,24 @@ func (rmse RMSE) NeedsProbabilities() bool {
 func (rmse RMSE) String() string {
 	return "rmse"
 }
+
+// Residuals computes (yPred[i] - yTrue[i])².
+func (rmse RMSE) Residuals(yTrue, yPred []float64) ([]float64, error) {
+	if len(yTrue) != len(yPred) {
+		return nil, &errMismatchedLengths{len(yTrue), len(yPred)}
+	}
+	var res = make([]float64, len(yTrue))
+	for i, y := range yTrue {
+		res[i] = math.Pow(yPred[i]-y, 2)
+	}
+	return res, nil
+}
+
+// Apply32 is the single precision version of Apply.
+func (rmse RMSE) Apply32(yTrue, yPred, weights []float32) (float64, error) {
+	var mse, err = MSE{}.Apply32(yTrue, yPred, weights)
+	if err != nil {
+		return math.Inf(1), err
+	}
+	return math.Pow(mse, 0.5), nil
+}
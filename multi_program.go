@@ -0,0 +1,269 @@
+package xgp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/MaxHalford/eaopt"
+	"github.com/gonum/floats"
+
+	"github.com/MaxHalford/xgp/metrics"
+	"github.com/MaxHalford/xgp/op"
+)
+
+// A MultiProgram is used for multi-class classification. It contains one
+// Operator per class. The class probabilities are obtained by applying the
+// softmax function to the outputs of the Operators.
+type MultiProgram struct {
+	*GP
+	Ops       []op.Operator
+	Classes   []float64
+	residuals []float64
+}
+
+// String formatting.
+func (mp MultiProgram) String() string {
+	var lines = make([]string, len(mp.Ops))
+	for i, operator := range mp.Ops {
+		lines[i] = fmt.Sprintf("%s: %s", strconv.FormatFloat(mp.Classes[i], 'f', -1, 64), operator)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PredictProba returns the probability of each class. The output contains
+// one slice per class, ordered in the same way as the Classes field.
+func (mp MultiProgram) PredictProba(X [][]float64) ([][]float64, error) {
+	// Make raw predictions for each class
+	var outputs = make([][]float64, len(mp.Ops))
+	for i, operator := range mp.Ops {
+		outputs[i] = evalOp(operator, X)
+	}
+	return softmax(outputs)
+}
+
+// softmax turns the raw outputs of the Operators into class probabilities by
+// applying the softmax function row by row. The outputs are modified in
+// place.
+func softmax(outputs [][]float64) ([][]float64, error) {
+	// Check the predictions don't contain any NaNs
+	for _, y := range outputs {
+		if floats.HasNaN(y) {
+			return nil, errors.New("yPred contains NaNs")
+		}
+	}
+	for j := range outputs[0] {
+		var max = math.Inf(-1)
+		for i := range outputs {
+			max = math.Max(max, outputs[i][j])
+		}
+		var sum float64
+		for i := range outputs {
+			outputs[i][j] = math.Exp(outputs[i][j] - max)
+			sum += outputs[i][j]
+		}
+		for i := range outputs {
+			outputs[i][j] /= sum
+		}
+	}
+	return outputs, nil
+}
+
+// Predict returns the most probable class for each row in X. Class
+// probabilities have to be obtained with the PredictProba method.
+func (mp MultiProgram) Predict(X [][]float64, proba bool) ([]float64, error) {
+	if proba {
+		return nil, errors.New("Class probabilities have to be obtained with PredictProba")
+	}
+	probas, err := mp.PredictProba(X)
+	if err != nil {
+		return nil, err
+	}
+	return mp.mostProbable(probas), nil
+}
+
+// mostProbable returns the most probable class for each row.
+func (mp MultiProgram) mostProbable(probas [][]float64) []float64 {
+	var yPred = make([]float64, len(probas[0]))
+	for j := range yPred {
+		var best int
+		for i := range probas {
+			if probas[i][j] > probas[best][j] {
+				best = i
+			}
+		}
+		yPred[j] = mp.Classes[best]
+	}
+	return yPred
+}
+
+// applyMetric applies a Metric to the class probabilities predicted by the
+// MultiProgram. Metrics which need probabilities have to be ProbaMetrics,
+// such as LogLoss which then becomes the multinomial log-loss, whereas the
+// other Metrics are applied to the most probable classes.
+func (mp MultiProgram) applyMetric(metric metrics.Metric, probas [][]float64, Y, W []float64) (float64, error) {
+	if !metric.NeedsProbabilities() {
+		return metric.Apply(Y, mp.mostProbable(probas), W)
+	}
+	pm, ok := metric.(metrics.ProbaMetric)
+	if !ok {
+		return math.Inf(1), fmt.Errorf("The '%s' metric can't be used for multi-class classification", metric.String())
+	}
+	return pm.ApplyProba(Y, probas, mp.Classes, W)
+}
+
+// trainResiduals returns the error made on each training sample if the
+// selection method needs them and nil otherwise, see GP.residuals.
+func (mp MultiProgram) trainResiduals(probas [][]float64) ([]float64, error) {
+	var gp = mp.GP
+	if pm, ok := gp.residualMetric.(metrics.ProbaMetric); ok && pm.NeedsProbabilities() {
+		return pm.ResidualsProba(gp.Y, probas, mp.Classes)
+	}
+	return gp.residuals(mp.mostProbable(probas))
+}
+
+// Evaluate is required to implement eaopt.Genome. The fitness is looked up
+// in the GP's cache beforehand if there is one.
+func (mp *MultiProgram) Evaluate() (float64, error) {
+	var cache = mp.GP.cache
+	if cache == nil {
+		return mp.evaluate()
+	}
+	var hash = hashOps(mp.Ops)
+	if entry, ok := cache.get(hash, mp.Ops...); ok {
+		mp.residuals = entry.residuals
+		return entry.fitness, nil
+	}
+	fitness, err := mp.evaluate()
+	if err != nil {
+		return fitness, err
+	}
+	// The Operators are copied because the MultiProgram might be mutated
+	cache.add(cacheEntry{
+		hash:      hash,
+		ops:       append([]op.Operator{}, mp.Ops...),
+		fitness:   fitness,
+		residuals: mp.residuals,
+	})
+	return fitness, nil
+}
+
+// evaluate computes the fitness of a MultiProgram on the training set. Each
+// Operator is evaluated in the same way as the Operator of a Program. The
+// class probabilities are used if the GP's LossMetric needs them, which means
+// that the Operators are trained on the output of the softmax function.
+func (mp *MultiProgram) evaluate() (float64, error) {
+	// For convenience
+	gp := mp.GP
+	gp.countEvaluation()
+	// Run the training set through the MultiProgram
+	var outputs = make([][]float64, len(mp.Ops))
+	for i, operator := range mp.Ops {
+		outputs[i] = gp.evalTrain(operator)
+	}
+	probas, err := softmax(outputs)
+	if err != nil {
+		return math.Inf(1), err
+	}
+	// Use the Metric defined in the GP
+	fitness, err := mp.applyMetric(gp.LossMetric, probas, gp.Y, gp.W)
+	if err != nil {
+		return math.Inf(1), err
+	}
+	if mp.residuals, err = mp.trainResiduals(probas); err != nil {
+		return math.Inf(1), err
+	}
+	if math.IsNaN(fitness) {
+		return math.Inf(1), nil
+	}
+	// Apply the parsimony coefficient
+	if gp.ParsimonyCoeff != 0 && !gp.MultiObjective {
+		var n uint
+		for _, operator := range mp.Ops {
+			n += op.CountOps(operator)
+		}
+		fitness += gp.ParsimonyCoeff * float64(n)
+	}
+	return fitness, nil
+}
+
+// Mutate is required to implement eaopt.Genome. Only the Operator of one
+// randomly chosen class is mutated.
+func (mp *MultiProgram) Mutate(rng *rand.Rand) {
+	var i = rng.Intn(len(mp.Ops))
+	mp.Ops[i] = mp.GP.mutate(mp.Ops[i], rng)
+}
+
+// Crossover is required to implement eaopt.Genome. Subtree crossover is
+// applied to the Operators of one randomly chosen class.
+func (mp *MultiProgram) Crossover(mp2 eaopt.Genome, rng *rand.Rand) {
+	var (
+		other          = mp2.(*MultiProgram)
+		i              = rng.Intn(len(mp.Ops))
+		newOp1, newOp2 = mp.GP.SubtreeCrossover.Apply(mp.Ops[i], other.Ops[i], rng)
+	)
+	mp.Ops[i] = mp.GP.simplify(newOp1)
+	other.Ops[i] = mp.GP.simplify(newOp2)
+}
+
+// Clone is required to implement eaopt.Genome.
+func (mp MultiProgram) Clone() eaopt.Genome {
+	var ops = make([]op.Operator, len(mp.Ops))
+	copy(ops, mp.Ops)
+	return &MultiProgram{
+		GP:        mp.GP,
+		Ops:       ops,
+		Classes:   mp.Classes,
+		residuals: mp.residuals,
+	}
+}
+
+type serialMultiProgram struct {
+	Ops        []op.SerialOp `json:"ops"`
+	Classes    []float64     `json:"classes"`
+	LossMetric string        `json:"loss_metric"`
+}
+
+// MarshalJSON serializes a MultiProgram.
+func (mp MultiProgram) MarshalJSON() ([]byte, error) {
+	var ops = make([]op.SerialOp, len(mp.Ops))
+	for i, operator := range mp.Ops {
+		ops[i] = op.SerializeOp(operator)
+	}
+	return json.Marshal(&serialMultiProgram{
+		Ops:        ops,
+		Classes:    mp.Classes,
+		LossMetric: mp.GP.LossMetric.String(),
+	})
+}
+
+// UnmarshalJSON parses a MultiProgram.
+func (mp *MultiProgram) UnmarshalJSON(bytes []byte) error {
+	var serial = &serialMultiProgram{}
+	if err := json.Unmarshal(bytes, serial); err != nil {
+		return err
+	}
+	if len(serial.Ops) != len(serial.Classes) {
+		return errors.New("The number of operators doesn't match the number of classes")
+	}
+	loss, err := metrics.ParseMetric(serial.LossMetric, 1)
+	if err != nil {
+		return err
+	}
+	var ops = make([]op.Operator, len(serial.Ops))
+	for i, serialOp := range serial.Ops {
+		operator, err := op.ParseOp(serialOp)
+		if err != nil {
+			return err
+		}
+		ops[i] = operator
+	}
+	mp.Ops = ops
+	mp.Classes = serial.Classes
+	mp.GP = &GP{LossMetric: loss}
+	return nil
+}
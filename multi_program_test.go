@@ -0,0 +1,280 @@
+package xgp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/MaxHalford/xgp/metrics"
+	"github.com/MaxHalford/xgp/op"
+)
+
+func TestMultiProgramPredict(t *testing.T) {
+	var testCases = []struct {
+		X           [][]float64
+		program     MultiProgram
+		proba       bool
+		y           []float64
+		raisesError bool
+	}{
+		{
+			X: [][]float64{
+				[]float64{1, 2, 3},
+				[]float64{3, 2, 1},
+			},
+			program: MultiProgram{
+				Ops:     []op.Operator{op.Var{0}, op.Var{1}},
+				Classes: []float64{0, 1},
+			},
+			proba:       false,
+			y:           []float64{1, 0, 0},
+			raisesError: false,
+		},
+		{
+			X: [][]float64{
+				[]float64{1, 2, 3},
+				[]float64{3, 2, 1},
+			},
+			program: MultiProgram{
+				Ops:     []op.Operator{op.Var{0}, op.Var{1}, op.Const{2.5}},
+				Classes: []float64{4, 5, 6},
+			},
+			proba:       false,
+			y:           []float64{5, 6, 4},
+			raisesError: false,
+		},
+		{
+			X: [][]float64{
+				[]float64{1, 2, 3},
+				[]float64{3, 2, 1},
+			},
+			program: MultiProgram{
+				Ops:     []op.Operator{op.Var{0}, op.Var{1}, op.Const{2.5}},
+				Classes: []float64{4, 5, 6},
+			},
+			proba:       true,
+			y:           nil,
+			raisesError: true,
+		},
+		{
+			X: [][]float64{
+				[]float64{math.NaN(), 2, 3},
+				[]float64{3, 2, 1},
+			},
+			program: MultiProgram{
+				Ops:     []op.Operator{op.Var{0}, op.Var{1}, op.Const{2.5}},
+				Classes: []float64{4, 5, 6},
+			},
+			proba:       false,
+			y:           nil,
+			raisesError: true,
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var y, err = tc.program.Predict(tc.X, tc.proba)
+			if (err != nil) != tc.raisesError {
+				t.Errorf("Expected nil error, got %s", err)
+			}
+			if len(y) != len(tc.y) {
+				t.Errorf("Expected %d predictions, got %d", len(tc.y), len(y))
+				return
+			}
+			for j := range y {
+				if y[j] != tc.y[j] {
+					t.Errorf("Expected %.5f, got %.5f", tc.y[j], y[j])
+				}
+			}
+		})
+	}
+}
+
+func TestMultiProgramPredictProba(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 1000},
+			[]float64{3, 2, 1, -1000},
+		}
+		program = MultiProgram{
+			Ops:     []op.Operator{op.Var{0}, op.Var{1}, op.Const{0}},
+			Classes: []float64{0, 1, 2},
+		}
+		probas, err = program.PredictProba(X)
+	)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(probas) != 3 {
+		t.Errorf("Expected 3 classes, got %d", len(probas))
+		return
+	}
+	for j := range X[0] {
+		var sum float64
+		for i := range probas {
+			sum += probas[i][j]
+		}
+		if math.Abs(sum-1) > 10e-10 {
+			t.Errorf("Expected probabilities to sum to 1, got %.5f", sum)
+		}
+	}
+	// Check the computation is numerically stable
+	if probas[0][3] != 1 {
+		t.Errorf("Expected 1, got %.5f", probas[0][3])
+	}
+	// The first and second Operators output the same value on the second row
+	if probas[0][1] != probas[1][1] {
+		t.Errorf("Expected %.5f, got %.5f", probas[0][1], probas[1][1])
+	}
+}
+
+func TestMultiProgramMarshalJSON(t *testing.T) {
+	var (
+		prog = MultiProgram{
+			Ops:     []op.Operator{op.Add{op.Var{0}, op.Const{42}}, op.Var{1}, op.Const{3}},
+			Classes: []float64{1, 2, 3},
+			GP:      &GP{LossMetric: metrics.Accuracy{}},
+		}
+		bytes, err = prog.MarshalJSON()
+	)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var newProg = MultiProgram{}
+	err = newProg.UnmarshalJSON(bytes)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if newProg.String() != prog.String() {
+		t.Errorf("Expected %s, got %s", prog, newProg)
+		return
+	}
+}
+
+func TestGPMultiClass(t *testing.T) {
+	var conf = NewDefaultGPConfig()
+	conf.LossMetric = metrics.Accuracy{}
+	conf.RNG = rand.New(rand.NewSource(42))
+	conf.NIndividuals = 30
+	conf.NGenerations = 10
+	conf.MinHeight = 1
+	conf.MaxHeight = 3
+	var gp, err = conf.NewGP()
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5, 6},
+			[]float64{6, 5, 4, 3, 2, 1},
+		}
+		Y = []float64{0, 0, 1, 1, 2, 2}
+	)
+	if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if _, err = gp.BestProgram(); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	best, err := gp.BestMultiProgram()
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(best.Ops) != 3 {
+		t.Errorf("Expected 3 operators, got %d", len(best.Ops))
+	}
+	yPred, err := gp.Predict(X, false)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	for _, y := range yPred {
+		if y != 0 && y != 1 && y != 2 {
+			t.Errorf("Expected a class label, got %.5f", y)
+		}
+	}
+	probas, err := gp.PredictProba(X)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(probas) != 3 {
+		t.Errorf("Expected 3 classes, got %d", len(probas))
+	}
+}
+
+func TestGPMultiClassProba(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5, 6},
+			[]float64{6, 5, 4, 3, 2, 1},
+		}
+		Y         = []float64{0, 0, 1, 1, 2, 2}
+		testCases = []struct {
+			selection    string
+			evalMetric   metrics.Metric
+			polishMethod string
+			raisesError  bool
+		}{
+			{selection: "tournament", evalMetric: metrics.LogLoss{}, polishMethod: "cmaes", raisesError: false},
+			{selection: "lexicase", evalMetric: metrics.Accuracy{}, polishMethod: "cmaes", raisesError: false},
+			{selection: "tournament", evalMetric: metrics.ROCAUC{}, polishMethod: "cmaes", raisesError: true},
+			{selection: "tournament", evalMetric: metrics.LogLoss{}, polishMethod: "bfgs", raisesError: true},
+		}
+	)
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var conf = NewDefaultGPConfig()
+			conf.LossMetric = metrics.LogLoss{}
+			conf.EvalMetric = tc.evalMetric
+			conf.Selection = tc.selection
+			conf.PolishMethod = tc.polishMethod
+			conf.RNG = rand.New(rand.NewSource(42))
+			conf.NIndividuals = 30
+			conf.NGenerations = 10
+			conf.MinHeight = 1
+			conf.MaxHeight = 3
+			var gp, err = conf.NewGP()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			err = gp.Fit(X, Y, nil, nil, nil, nil, false)
+			if (err != nil) != tc.raisesError {
+				t.Errorf("Expected nil error, got %s", err)
+			}
+			if tc.raisesError {
+				return
+			}
+			probas, err := gp.PredictProba(X)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			// The Operators are trained on the softmax output, hence the
+			// multinomial log-loss should be lower than the one obtained by
+			// predicting the same probability for each class
+			loss, err := metrics.LogLoss{}.ApplyProba(Y, probas, []float64{0, 1, 2}, nil)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if loss >= math.Log(3) {
+				t.Errorf("Expected a loss lower than %.5f, got %.5f", math.Log(3), loss)
+			}
+			// Class probabilities can only be obtained with PredictProba
+			if _, err = gp.Predict(X, true); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+			if _, err = gp.PredictPartial([]float64{1, 6}, true); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package xgp
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/MaxHalford/eaopt"
+	"github.com/MaxHalford/xgp/op"
+)
+
+// objectives are the values minimized in multi-objective mode.
+type objectives struct {
+	loss       float64
+	complexity float64
+}
+
+// dominates indicates if a is at least as good as b on both objectives and
+// strictly better on one of them.
+func (a objectives) dominates(b objectives) bool {
+	return a.loss <= b.loss && a.complexity <= b.complexity &&
+		(a.loss < b.loss || a.complexity < b.complexity)
+}
+
+// complexity returns the complexity of a Program or of a MultiProgram. The
+// complexity of a MultiProgram is the sum of the complexities of it's
+// Operators.
+func (gp GP) complexity(genome eaopt.Genome) float64 {
+	var measure = gp.Complexity
+	if measure == nil {
+		measure = func(operator op.Operator) float64 { return float64(op.CountOps(operator)) }
+	}
+	switch genome := genome.(type) {
+	case *Program:
+		return measure(genome.Op)
+	case *MultiProgram:
+		var c float64
+		for _, operator := range genome.Ops {
+			c += measure(operator)
+		}
+		return c
+	}
+	return math.Inf(1)
+}
+
+func (gp GP) objectives(indis eaopt.Individuals) []objectives {
+	var objs = make([]objectives, len(indis))
+	for i, indi := range indis {
+		objs[i] = objectives{loss: indi.Fitness, complexity: gp.complexity(indi.Genome)}
+	}
+	return objs
+}
+
+// nonDominatedSort sorts the solutions into successive Pareto fronts. Each
+// front contains the positions of the solutions that are only dominated by
+// solutions of the previous fronts.
+func nonDominatedSort(objs []objectives) [][]int {
+	var (
+		dominated = make([][]int, len(objs))
+		counts    = make([]int, len(objs))
+		fronts    = make([][]int, 0)
+		front     = make([]int, 0)
+	)
+	for i := range objs {
+		for j := range objs {
+			if objs[i].dominates(objs[j]) {
+				dominated[i] = append(dominated[i], j)
+			} else if objs[j].dominates(objs[i]) {
+				counts[i]++
+			}
+		}
+		if counts[i] == 0 {
+			front = append(front, i)
+		}
+	}
+	for len(front) > 0 {
+		fronts = append(fronts, front)
+		var next = make([]int, 0)
+		for _, i := range front {
+			for _, j := range dominated[i] {
+				counts[j]--
+				if counts[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		front = next
+	}
+	return fronts
+}
+
+// crowdingDistances returns the crowding distance of each solution of a
+// front. The boundary solutions of each objective have an infinite distance.
+func crowdingDistances(objs []objectives, front []int) []float64 {
+	var (
+		distances = make([]float64, len(front))
+		order     = make([]int, len(front))
+		getters   = []func(o objectives) float64{
+			func(o objectives) float64 { return o.loss },
+			func(o objectives) float64 { return o.complexity },
+		}
+	)
+	for _, get := range getters {
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return get(objs[front[order[a]]]) < get(objs[front[order[b]]])
+		})
+		var (
+			min  = get(objs[front[order[0]]])
+			max  = get(objs[front[order[len(order)-1]]])
+			span = max - min
+		)
+		distances[order[0]] = math.Inf(1)
+		distances[order[len(order)-1]] = math.Inf(1)
+		if span == 0 || math.IsInf(span, 0) || math.IsNaN(span) {
+			continue
+		}
+		for i := 1; i < len(order)-1; i++ {
+			distances[order[i]] += (get(objs[front[order[i+1]]]) - get(objs[front[order[i-1]]])) / span
+		}
+	}
+	return distances
+}
+
+// rankAndCrowd returns the rank of the front of each solution along with it's
+// crowding distance within it's front.
+func rankAndCrowd(objs []objectives) ([]int, []float64) {
+	var (
+		ranks    = make([]int, len(objs))
+		crowding = make([]float64, len(objs))
+	)
+	for rank, front := range nonDominatedSort(objs) {
+		for i, d := range crowdingDistances(objs, front) {
+			ranks[front[i]] = rank
+			crowding[front[i]] = d
+		}
+	}
+	return ranks, crowding
+}
+
+// nsga2Model is an eaopt.Model which implements NSGA-II. The loss and the
+// complexity of the Programs are minimized together; offsprings are produced
+// by binary tournaments on the rank and the crowding distance, after which
+// the best individuals amongst the parents and the offsprings are kept.
+type nsga2Model struct {
+	gp         *GP
+	pMutate    float64
+	pCrossover float64
+}
+
+// Apply is necessary to implement eaopt.Model.
+func (mod nsga2Model) Apply(pop *eaopt.Population) error {
+	// The parents have to be evaluated again if the training rows have been
+	// resampled
+	if err := evaluateParallel(pop.Individuals); err != nil {
+		return err
+	}
+	var (
+		n              = len(pop.Individuals)
+		ranks, crowded = rankAndCrowd(mod.gp.objectives(pop.Individuals))
+		tournament     = func() eaopt.Individual {
+			var i, j = pop.RNG.Intn(n), pop.RNG.Intn(n)
+			if ranks[j] < ranks[i] || (ranks[j] == ranks[i] && crowded[j] > crowded[i]) {
+				i = j
+			}
+			return pop.Individuals[i].Clone(pop.RNG)
+		}
+		offsprings = make(eaopt.Individuals, n)
+	)
+
+	// Generate the offsprings
+	for i := range offsprings {
+		var offspring = tournament()
+		// Roll a dice and decide what to do
+		var dice = pop.RNG.Float64()
+		if dice < mod.pMutate {
+			offspring.Mutate(pop.RNG)
+		} else if dice < (mod.pMutate + mod.pCrossover) {
+			offspring.Crossover(tournament(), pop.RNG)
+		}
+		offsprings[i] = offspring
+	}
+	if err := mod.gp.memetic(offsprings, pop.RNG); err != nil {
+		return err
+	}
+	if err := evaluateParallel(offsprings); err != nil {
+		return err
+	}
+
+	// Keep the best fronts amongst the parents and the offsprings, the last
+	// front being truncated according to the crowding distance
+	var (
+		merged   = append(append(make(eaopt.Individuals, 0, 2*n), pop.Individuals...), offsprings...)
+		objs     = mod.gp.objectives(merged)
+		selected = make([]int, 0, n)
+	)
+	for _, front := range nonDominatedSort(objs) {
+		if len(selected)+len(front) <= n {
+			selected = append(selected, front...)
+			continue
+		}
+		var distances = crowdingDistances(objs, front)
+		var order = make([]int, len(front))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return distances[order[a]] > distances[order[b]] })
+		for _, i := range order[:n-len(selected)] {
+			selected = append(selected, front[i])
+		}
+		break
+	}
+	for i, idx := range selected {
+		pop.Individuals[i] = merged[idx]
+	}
+	return nil
+}
+
+// Validate is necessary to implement eaopt.Model.
+func (mod nsga2Model) Validate() error {
+	return nil
+}
+
+// evaluateParallel evaluates Individuals concurrently.
+func evaluateParallel(indis eaopt.Individuals) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(indis))
+	)
+	for i := range indis {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = indis[i].Evaluate()
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// paretoFront returns the Individuals of all the populations that are not
+// dominated by any other Individual, ordered by increasing complexity. Only
+// one Individual is kept for each pair of objectives.
+func (gp *GP) paretoFront() (eaopt.Individuals, []float64) {
+	var indis = make(eaopt.Individuals, 0)
+	for _, pop := range gp.GA.Populations {
+		indis = append(indis, pop.Individuals...)
+	}
+	var (
+		objs  = gp.objectives(indis)
+		front = nonDominatedSort(objs)[0]
+		seen  = make(map[objectives]bool)
+		pf    = make(eaopt.Individuals, 0, len(front))
+		cs    = make([]float64, 0, len(front))
+	)
+	sort.SliceStable(front, func(a, b int) bool {
+		return objs[front[a]].complexity < objs[front[b]].complexity
+	})
+	for _, i := range front {
+		if seen[objs[i]] {
+			continue
+		}
+		seen[objs[i]] = true
+		pf = append(pf, indis[i])
+		cs = append(cs, objs[i].complexity)
+	}
+	return pf, cs
+}
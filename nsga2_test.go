@@ -0,0 +1,134 @@
+package xgp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestNonDominatedSort(t *testing.T) {
+	var testCases = []struct {
+		objs   []objectives
+		fronts [][]int
+	}{
+		{
+			objs: []objectives{
+				{loss: 1, complexity: 1},
+			},
+			fronts: [][]int{{0}},
+		},
+		{
+			objs: []objectives{
+				{loss: 1, complexity: 3},
+				{loss: 2, complexity: 2},
+				{loss: 3, complexity: 1},
+			},
+			fronts: [][]int{{0, 1, 2}},
+		},
+		{
+			objs: []objectives{
+				{loss: 3, complexity: 3},
+				{loss: 1, complexity: 1},
+				{loss: 2, complexity: 2},
+				{loss: 2, complexity: 2},
+			},
+			fronts: [][]int{{1}, {2, 3}, {0}},
+		},
+		{
+			objs: []objectives{
+				{loss: math.Inf(1), complexity: 1},
+				{loss: 1, complexity: 5},
+				{loss: 2, complexity: 5},
+			},
+			fronts: [][]int{{0, 1}, {2}},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var fronts = nonDominatedSort(tc.objs)
+			if !reflect.DeepEqual(fronts, tc.fronts) {
+				t.Errorf("Expected %v, got %v", tc.fronts, fronts)
+			}
+		})
+	}
+}
+
+func TestCrowdingDistances(t *testing.T) {
+	var testCases = []struct {
+		objs      []objectives
+		front     []int
+		distances []float64
+	}{
+		{
+			objs: []objectives{
+				{loss: 1, complexity: 1},
+			},
+			front:     []int{0},
+			distances: []float64{math.Inf(1)},
+		},
+		{
+			objs: []objectives{
+				{loss: 1, complexity: 5},
+				{loss: 2, complexity: 4},
+				{loss: 4, complexity: 2},
+				{loss: 5, complexity: 1},
+			},
+			front:     []int{0, 1, 2, 3},
+			distances: []float64{math.Inf(1), 1.5, 1.5, math.Inf(1)},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var distances = crowdingDistances(tc.objs, tc.front)
+			for j := range distances {
+				if distances[j] != tc.distances[j] {
+					t.Errorf("Expected %.5f, got %.5f", tc.distances[j], distances[j])
+				}
+			}
+		})
+	}
+}
+
+func TestGPMultiObjective(t *testing.T) {
+	var conf = NewDefaultGPConfig()
+	conf.RNG = rand.New(rand.NewSource(42))
+	conf.NIndividuals = 50
+	conf.NGenerations = 10
+	conf.MultiObjective = true
+	conf.PolishBest = false
+	var gp, err = conf.NewGP()
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y = []float64{7, 13, 21, 31, 43}
+	)
+	if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	progs, points, err := gp.ParetoFront()
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if len(progs) == 0 || len(progs) != len(points) {
+		t.Errorf("Expected as many programs as points, got %d and %d", len(progs), len(points))
+		return
+	}
+	for i := 1; i < len(points); i++ {
+		if points[i].Complexity <= points[i-1].Complexity || points[i].Loss >= points[i-1].Loss {
+			t.Errorf("Expected a trade-off between %v and %v", points[i-1], points[i])
+		}
+	}
+	if _, _, err = gp.MultiParetoFront(); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
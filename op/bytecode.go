@@ -0,0 +1,277 @@
+package op
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gonum/floats"
+)
+
+// chunkSize is the number of rows evaluated at once by a Bytecode.
+const chunkSize = 256
+
+type opcode uint8
+
+const (
+	opConst opcode = iota
+	opVar
+	opAbs
+	opAdd
+	opCos
+	opDiv
+	opExp
+	opIf
+	opInv
+	opLog
+	opMax
+	opMin
+	opMul
+	opNeg
+	opPow
+	opSigmoid
+	opSin
+	opSqrt
+	opSquare
+	opSub
+	opTanh
+)
+
+type instruction struct {
+	code  opcode
+	index uint
+	value float64
+}
+
+// A Bytecode is an Operator compiled into a sequence of instructions for a
+// stack machine. The instructions are the nodes of the Operator in post-order.
+// The rows are evaluated in chunks, each instruction working on buffers of
+// chunkSize values which are reused from one chunk to another and from one
+// evaluation to another. The outputs are exactly the same as those of Eval.
+type Bytecode struct {
+	instructions []instruction
+	stackSize    int
+}
+
+// Compile turns an Operator into a Bytecode. An error is returned if the
+// Operator contains an Operator which is not part of this package.
+func Compile(op Operator) (*Bytecode, error) {
+	var bc = &Bytecode{}
+	if err := bc.compile(op, 0); err != nil {
+		return nil, err
+	}
+	return bc, nil
+}
+
+func (bc *Bytecode) compile(op Operator, depth int) error {
+	// The operands are pushed onto the stack on top of each other
+	for i := uint(0); i < op.Arity(); i++ {
+		if err := bc.compile(op.Operand(i), depth+int(i)); err != nil {
+			return err
+		}
+	}
+	if depth+1 > bc.stackSize {
+		bc.stackSize = depth + 1
+	}
+	var inst instruction
+	switch op := op.(type) {
+	case Const:
+		// Const.Eval adds the value to 0, which turns -0 into 0
+		var zero float64
+		inst = instruction{code: opConst, value: zero + op.Value}
+	case Var:
+		inst = instruction{code: opVar, index: op.Index}
+	case Abs:
+		inst.code = opAbs
+	case Add:
+		inst.code = opAdd
+	case Cos:
+		inst.code = opCos
+	case Div:
+		inst.code = opDiv
+	case Exp:
+		inst.code = opExp
+	case If:
+		inst.code = opIf
+	case Inv:
+		inst.code = opInv
+	case Log:
+		inst.code = opLog
+	case Max:
+		inst.code = opMax
+	case Min:
+		inst.code = opMin
+	case Mul:
+		inst.code = opMul
+	case Neg:
+		inst.code = opNeg
+	case Pow:
+		inst.code = opPow
+	case Sigmoid:
+		inst.code = opSigmoid
+	case Sin:
+		inst.code = opSin
+	case Sqrt:
+		inst.code = opSqrt
+	case Square:
+		inst.code = opSquare
+	case Sub:
+		inst.code = opSub
+	case Tanh:
+		inst.code = opTanh
+	default:
+		return fmt.Errorf("Operators of type %T can't be compiled", op)
+	}
+	bc.instructions = append(bc.instructions, inst)
+	return nil
+}
+
+// buffers contains the stacks used by Bytecodes so that they can be reused.
+var buffers = sync.Pool{
+	New: func() interface{} { return new([]float64) },
+}
+
+// Eval evaluates the Bytecode on a dataset.
+func (bc *Bytecode) Eval(X [][]float64) []float64 {
+	var (
+		y   = make([]float64, len(X[0]))
+		buf = buffers.Get().(*[]float64)
+	)
+	if cap(*buf) < bc.stackSize*chunkSize {
+		*buf = make([]float64, bc.stackSize*chunkSize)
+	}
+	var stack = make([][]float64, bc.stackSize)
+	for start := 0; start < len(y); start += chunkSize {
+		var end = start + chunkSize
+		if end > len(y) {
+			end = len(y)
+		}
+		for i := range stack {
+			stack[i] = (*buf)[i*chunkSize : i*chunkSize+end-start]
+		}
+		bc.evalChunk(X, start, stack)
+		copy(y[start:end], stack[0])
+	}
+	buffers.Put(buf)
+	return y
+}
+
+// evalChunk evaluates the rows of X starting at start, the number of rows
+// being given by the length of the buffers. The output is stored in the first
+// buffer of the stack.
+func (bc *Bytecode) evalChunk(X [][]float64, start int, stack [][]float64) {
+	var top = -1
+	for _, inst := range bc.instructions {
+		switch inst.code {
+		case opConst:
+			top++
+			var x = stack[top]
+			for i := range x {
+				x[i] = inst.value
+			}
+		case opVar:
+			top++
+			copy(stack[top], X[inst.index][start:])
+		case opAbs:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = math.Abs(xi)
+			}
+		case opCos:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = math.Cos(xi)
+			}
+		case opExp:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = math.Exp(xi)
+			}
+		case opInv:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = safeDiv(1, xi)
+			}
+		case opLog:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = safeLog(xi)
+			}
+		case opNeg:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = -xi
+			}
+		case opSigmoid:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = sigmoid(xi)
+			}
+		case opSin:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = math.Sin(xi)
+			}
+		case opSqrt:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = safeSqrt(xi)
+			}
+		case opSquare:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = math.Pow(xi, 2)
+			}
+		case opTanh:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = math.Tanh(xi)
+			}
+		case opIf:
+			// The condition, the lower branch and the upper branch are on
+			// top of each other
+			var cond, lower, upper = stack[top-2], stack[top-1], stack[top]
+			for i, c := range cond {
+				if c > 0 {
+					cond[i] = upper[i]
+				} else {
+					cond[i] = lower[i]
+				}
+			}
+			top -= 2
+		default:
+			// The same functions as in Eval are used so that NaNs are
+			// propagated in the same way
+			var x, y = stack[top-1], stack[top]
+			switch inst.code {
+			case opAdd:
+				floats.Add(x, y)
+			case opDiv:
+				for i, yi := range y {
+					x[i] = safeDiv(x[i], yi)
+				}
+			case opMax:
+				for i, yi := range y {
+					if yi > x[i] {
+						x[i] = yi
+					}
+				}
+			case opMin:
+				for i, yi := range y {
+					if yi < x[i] {
+						x[i] = yi
+					}
+				}
+			case opMul:
+				floats.Mul(x, y)
+			case opPow:
+				for i, yi := range y {
+					x[i] = safePow(x[i], yi)
+				}
+			case opSub:
+				floats.Sub(x, y)
+			}
+			top--
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package op
+
+import (
+	"math"
+	"sync"
+)
+
+// buffers32 contains the float32 stacks used by Bytecodes so that they can be
+// reused.
+var buffers32 = sync.Pool{
+	New: func() interface{} { return new([]float32) },
+}
+
+// Eval32 evaluates the Bytecode on a float32 dataset. The values are stored
+// with single precision, the functions which aren't arithmetic being computed
+// with double precision before being rounded. The outputs hence differ
+// slightly from those of Eval but take up half the memory.
+func (bc *Bytecode) Eval32(X [][]float32) []float32 {
+	var (
+		y   = make([]float32, len(X[0]))
+		buf = buffers32.Get().(*[]float32)
+	)
+	if cap(*buf) < bc.stackSize*chunkSize {
+		*buf = make([]float32, bc.stackSize*chunkSize)
+	}
+	var stack = make([][]float32, bc.stackSize)
+	for start := 0; start < len(y); start += chunkSize {
+		var end = start + chunkSize
+		if end > len(y) {
+			end = len(y)
+		}
+		for i := range stack {
+			stack[i] = (*buf)[i*chunkSize : i*chunkSize+end-start]
+		}
+		bc.evalChunk32(X, start, stack)
+		copy(y[start:end], stack[0])
+	}
+	buffers32.Put(buf)
+	return y
+}
+
+// unary32 applies a float64 function to float32 values.
+func unary32(x []float32, f func(float64) float64) {
+	for i, xi := range x {
+		x[i] = float32(f(float64(xi)))
+	}
+}
+
+// evalChunk32 is the float32 counterpart of evalChunk.
+func (bc *Bytecode) evalChunk32(X [][]float32, start int, stack [][]float32) {
+	var top = -1
+	for _, inst := range bc.instructions {
+		switch inst.code {
+		case opConst:
+			top++
+			var (
+				x     = stack[top]
+				value = float32(inst.value)
+			)
+			for i := range x {
+				x[i] = value
+			}
+		case opVar:
+			top++
+			copy(stack[top], X[inst.index][start:])
+		case opAbs:
+			unary32(stack[top], math.Abs)
+		case opCos:
+			unary32(stack[top], math.Cos)
+		case opExp:
+			unary32(stack[top], math.Exp)
+		case opInv:
+			var x = stack[top]
+			for i, xi := range x {
+				if xi == 0 {
+					x[i] = 1
+				} else {
+					x[i] = 1 / xi
+				}
+			}
+		case opLog:
+			unary32(stack[top], safeLog)
+		case opNeg:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = -xi
+			}
+		case opSigmoid:
+			unary32(stack[top], sigmoid)
+		case opSin:
+			unary32(stack[top], math.Sin)
+		case opSqrt:
+			unary32(stack[top], safeSqrt)
+		case opSquare:
+			var x = stack[top]
+			for i, xi := range x {
+				x[i] = xi * xi
+			}
+		case opTanh:
+			unary32(stack[top], math.Tanh)
+		case opIf:
+			var cond, lower, upper = stack[top-2], stack[top-1], stack[top]
+			for i, c := range cond {
+				if c > 0 {
+					cond[i] = upper[i]
+				} else {
+					cond[i] = lower[i]
+				}
+			}
+			top -= 2
+		default:
+			var x, y = stack[top-1], stack[top]
+			switch inst.code {
+			case opAdd:
+				for i, yi := range y {
+					x[i] += yi
+				}
+			case opDiv:
+				for i, yi := range y {
+					if yi == 0 {
+						x[i] = 1
+					} else {
+						x[i] /= yi
+					}
+				}
+			case opMax:
+				for i, yi := range y {
+					if yi > x[i] {
+						x[i] = yi
+					}
+				}
+			case opMin:
+				for i, yi := range y {
+					if yi < x[i] {
+						x[i] = yi
+					}
+				}
+			case opMul:
+				for i, yi := range y {
+					x[i] *= yi
+				}
+			case opPow:
+				// The power is checked once rounded because it might
+				// overflow with single precision
+				for i, yi := range y {
+					var p = float32(math.Pow(float64(x[i]), float64(yi)))
+					if p != p || math.IsInf(float64(p), 0) {
+						p = 1
+					}
+					x[i] = p
+				}
+			case opSub:
+				for i, yi := range y {
+					x[i] -= yi
+				}
+			}
+			top--
+		}
+	}
+}
+
+// EvalFloat32 evaluates an Operator on a float32 dataset. The Operator is
+// compiled into a Bytecode, if this isn't possible then it is evaluated with
+// Eval on a float64 copy of the dataset.
+func EvalFloat32(op Operator, X [][]float32) []float32 {
+	if bc, err := Compile(op); err == nil {
+		return bc.Eval32(X)
+	}
+	var X64 = make([][]float64, len(X))
+	for j, x := range X {
+		X64[j] = make([]float64, len(x))
+		for i, xi := range x {
+			X64[j][i] = float64(xi)
+		}
+	}
+	var (
+		y64 = op.Eval(X64)
+		y   = make([]float32, len(y64))
+	)
+	for i, yi := range y64 {
+		y[i] = float32(yi)
+	}
+	return y
+}
@@ -0,0 +1,63 @@
+package op
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestEvalFloat32(t *testing.T) {
+	var (
+		X   = [][]float64{make([]float64, chunkSize+17), make([]float64, chunkSize+17)}
+		X32 = [][]float32{make([]float32, chunkSize+17), make([]float32, chunkSize+17)}
+	)
+	for i := range X[0] {
+		X[0][i] = float64(i%7) - 3.25
+		X[1][i] = float64(i%5) * 0.5
+		X32[0][i] = float32(X[0][i])
+		X32[1][i] = float32(X[1][i])
+	}
+	var testCases = []Operator{
+		Const{1.1},
+		Var{1},
+		Abs{Var{0}},
+		Add{Var{0}, Var{1}},
+		Cos{Var{0}},
+		Div{Var{0}, Var{1}},
+		Exp{Var{0}},
+		If{Var{0}, Var{1}, Const{2}},
+		Inv{Var{1}},
+		Log{Var{0}},
+		Max{Var{0}, Var{1}},
+		Min{Var{0}, Var{1}},
+		Mul{Var{0}, Const{0.3}},
+		Neg{Var{0}},
+		Pow{Var{1}, Var{0}},
+		Pow{Const{-2}, Const{0.5}},
+		Sigmoid{Var{0}},
+		Sin{Var{0}},
+		Sqrt{Var{0}},
+		Square{Var{0}},
+		Sub{Var{0}, Var{1}},
+		Tanh{Var{0}},
+		Add{Var{0}, unknownOp{Var{1}}},
+	}
+	for i, op := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var (
+				expected = op.Eval(X)
+				y        = EvalFloat32(op, X32)
+			)
+			if len(y) != len(expected) {
+				t.Errorf("Expected %d values, got %d", len(expected), len(y))
+				return
+			}
+			for j := range y {
+				if math.Abs(float64(y[j])-expected[j]) > 1e-6*math.Max(1, math.Abs(expected[j])) {
+					t.Errorf("%s: expected %v at row %d, got %v", op, expected[j], j, y[j])
+					return
+				}
+			}
+		})
+	}
+}
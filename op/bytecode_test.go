@@ -0,0 +1,78 @@
+package op
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randOp generates a random Operator which uses every kind of Operator.
+func randOp(height int, nFeatures int, rng *rand.Rand) Operator {
+	if height == 0 || rng.Float64() < 0.2 {
+		if rng.Float64() < 0.5 {
+			return Const{[]float64{0, math.Copysign(0, -1), -1, 2.5, 1e300}[rng.Intn(5)]}
+		}
+		return Var{uint(rng.Intn(nFeatures))}
+	}
+	var funcs = []Operator{
+		Abs{}, Add{}, Cos{}, Div{}, Exp{}, If{}, Inv{}, Log{}, Max{}, Min{},
+		Mul{}, Neg{}, Pow{}, Sigmoid{}, Sin{}, Sqrt{}, Square{}, Sub{}, Tanh{},
+	}
+	var op = funcs[rng.Intn(len(funcs))]
+	for i := uint(0); i < op.Arity(); i++ {
+		op = op.SetOperand(i, randOp(height-1, nFeatures, rng))
+	}
+	return op
+}
+
+func TestBytecode(t *testing.T) {
+	var (
+		rng = rand.New(rand.NewSource(42))
+		X   = make([][]float64, 3)
+	)
+	// There are more rows than the chunk size and special values
+	for j := range X {
+		X[j] = make([]float64, 2*chunkSize+17)
+		for i := range X[j] {
+			X[j][i] = rng.NormFloat64() * 10
+		}
+		X[j][0] = 0
+		X[j][1] = math.Copysign(0, -1)
+		X[j][2] = math.Inf(1)
+		X[j][3] = math.Inf(-1)
+		X[j][4] = math.NaN()
+	}
+	for i := 0; i < 500; i++ {
+		var op = randOp(5, len(X), rng)
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var bc, err = Compile(op)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			var (
+				expected = op.Eval(X)
+				y        = bc.Eval(X)
+			)
+			if len(y) != len(expected) {
+				t.Errorf("Expected %d values, got %d", len(expected), len(y))
+				return
+			}
+			for j := range y {
+				if math.Float64bits(y[j]) != math.Float64bits(expected[j]) {
+					t.Errorf("%s: expected %v at row %d, got %v", op, expected[j], j, y[j])
+					return
+				}
+			}
+		})
+	}
+}
+
+type unknownOp struct{ Var }
+
+func TestCompileUnknown(t *testing.T) {
+	if _, err := Compile(Add{Var{0}, unknownOp{}}); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
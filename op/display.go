@@ -2,6 +2,7 @@ package op
 
 import (
 	"fmt"
+	"math"
 	"strings"
 )
 
@@ -87,9 +88,27 @@ func (displayer CodeDisplay) Apply(op Operator) string {
 	return strings.TrimRight(str, ", ") + ")"
 }
 
+// parenthesize wraps an Operator's string representation in parentheses if
+// it could otherwise be mistaken for part of a bigger expression. Inv is
+// concerned because it is formatted as a division.
 func parenthesize(op Operator) string {
-	if op.Arity() > 1 {
+	if _, ok := op.(Inv); ok || op.Arity() > 1 {
 		return fmt.Sprintf("(%s)", op.String())
 	}
 	return op.String()
 }
+
+// parenthesizeBase does the same as parenthesize but also wraps negative
+// values, including -0. It is used for the base of a power because powers
+// bind tighter than the unary minus, for example -2² is equal to -4.
+func parenthesizeBase(op Operator) string {
+	switch op := op.(type) {
+	case Neg:
+		return fmt.Sprintf("(%s)", op.String())
+	case Const:
+		if math.Signbit(op.Value) {
+			return fmt.Sprintf("(%s)", op.String())
+		}
+	}
+	return parenthesize(op)
+}
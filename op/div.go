@@ -102,7 +102,7 @@ func (div Div) Diff(i uint) Operator {
 		Sub{
 			Mul{div.Left.Diff(i), div.Right},
 			Mul{div.Left, div.Right.Diff(i)}},
-		div.Right,
+		Square{div.Right},
 	}
 }
 
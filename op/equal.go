@@ -0,0 +1,43 @@
+package op
+
+import (
+	"math"
+	"reflect"
+)
+
+// Equal indicates if two Operators have the same structure, the same Consts
+// and the same Vars. Unlike ==, Equal doesn't panic if an Operator holds
+// values which aren't comparable, such as slices, which can happen with
+// Operators registered with Register; such values are compared with
+// reflect.DeepEqual.
+func Equal(a, b Operator) bool {
+	if a.Arity() != b.Arity() || !nodeEqual(a, b) {
+		return false
+	}
+	for i := uint(0); i < a.Arity(); i++ {
+		if !Equal(a.Operand(i), b.Operand(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeEqual compares two Operators regardless of their operands.
+func nodeEqual(a, b Operator) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	switch a := a.(type) {
+	case Const:
+		// The bits are compared in order to be consistent with Hash
+		return math.Float64bits(a.Value) == math.Float64bits(b.(Const).Value)
+	case Var:
+		return a.Index == b.(Var).Index
+	}
+	// The operands are removed so that only the parameters are compared
+	for i := uint(0); i < a.Arity(); i++ {
+		a = a.SetOperand(i, nil)
+		b = b.SetOperand(i, nil)
+	}
+	return reflect.DeepEqual(a, b)
+}
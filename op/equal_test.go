@@ -0,0 +1,81 @@
+package op
+
+import (
+	"fmt"
+	"testing"
+)
+
+// scale multiplies it's operand by a factor. It holds a slice so that it isn't
+// comparable with ==.
+type scale struct {
+	Factor []float64
+	Op     Operator
+}
+
+func (s scale) Eval(X [][]float64) []float64 {
+	x := s.Op.Eval(X)
+	for i := range x {
+		x[i] *= s.Factor[0]
+	}
+	return x
+}
+
+func (s scale) Arity() uint {
+	return 1
+}
+
+func (s scale) Operand(i uint) Operator {
+	if i == 0 {
+		return s.Op
+	}
+	return nil
+}
+
+func (s scale) SetOperand(i uint, op Operator) Operator {
+	if i == 0 {
+		s.Op = op
+	}
+	return s
+}
+
+func (s scale) Simplify() Operator {
+	s.Op = s.Op.Simplify()
+	return s
+}
+
+func (s scale) Diff(i uint) Operator {
+	return scale{s.Factor, s.Op.Diff(i)}
+}
+
+func (s scale) Name() string {
+	return "scale"
+}
+
+func (s scale) String() string {
+	return fmt.Sprintf("scale(%s, %v)", s.Op, s.Factor)
+}
+
+func TestEqual(t *testing.T) {
+	var testCases = []struct {
+		op1, op2 Operator
+		equal    bool
+	}{
+		{Add{Var{0}, Const{1}}, Add{Var{0}, Const{1}}, true},
+		{Add{Var{0}, Const{1}}, Add{Const{1}, Var{0}}, false},
+		{Add{Var{0}, Const{1}}, Sub{Var{0}, Const{1}}, false},
+		{Add{Var{0}, Const{1}}, Add{Var{0}, Const{2}}, false},
+		{Var{1}, Const{1}, false},
+		{scale{[]float64{2}, Var{0}}, scale{[]float64{2}, Var{0}}, true},
+		{scale{[]float64{2}, Var{0}}, scale{[]float64{3}, Var{0}}, false},
+		{scale{[]float64{2}, Var{0}}, scale{[]float64{2}, Var{1}}, false},
+		{Add{scale{[]float64{2}, Var{0}}, Var{1}}, Add{scale{[]float64{2}, Var{0}}, Var{1}}, true},
+		{Add{scale{[]float64{2}, Var{0}}, Var{1}}, Add{Var{0}, Var{1}}, false},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			if equal := Equal(tc.op1, tc.op2); equal != tc.equal {
+				t.Errorf("Expected %t, got %t", tc.equal, equal)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+// The Exp operator.
+type Exp struct {
+	Op Operator
+}
+
+// Eval computes the exponential of each value.
+func (exp Exp) Eval(X [][]float64) []float64 {
+	x := exp.Op.Eval(X)
+	for i, xi := range x {
+		x[i] = math.Exp(xi)
+	}
+	return x
+}
+
+// Arity of Exp is 1.
+func (exp Exp) Arity() uint {
+	return 1
+}
+
+// Operand returns Exp's operand or nil.
+func (exp Exp) Operand(i uint) Operator {
+	if i == 0 {
+		return exp.Op
+	}
+	return nil
+}
+
+// SetOperand replaces Exp's operand if i is equal to 0.
+func (exp Exp) SetOperand(i uint, op Operator) Operator {
+	if i == 0 {
+		exp.Op = op
+	}
+	return exp
+}
+
+// Simplify Exp.
+func (exp Exp) Simplify() Operator {
+	exp.Op = exp.Op.Simplify()
+	switch op := exp.Op.(type) {
+	case Const:
+		return Const{math.Exp(op.Value)}
+	}
+	return exp
+}
+
+// Diff computes the following derivative: exp(u)' = u' * exp(u)
+func (exp Exp) Diff(i uint) Operator {
+	return Mul{exp.Op.Diff(i), exp}
+}
+
+// Name of Exp is "exp".
+func (exp Exp) Name() string {
+	return "exp"
+}
+
+// String formatting.
+func (exp Exp) String() string {
+	return fmt.Sprintf("exp(%s)", exp.Op)
+}
@@ -0,0 +1,34 @@
+package op
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExpSimplify(t *testing.T) {
+	var testCases = []struct {
+		in  Exp
+		out Operator
+	}{
+		{
+			in:  Exp{Mul{Add{Const{1}, Const{2}}, Var{0}}},
+			out: Exp{Mul{Const{3}, Var{0}}},
+		},
+		{
+			in:  Exp{Const{0}},
+			out: Const{1},
+		},
+		{
+			in:  Exp{Var{0}},
+			out: Exp{Var{0}},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			out := tc.in.Simplify()
+			if out != tc.out {
+				t.Errorf("Expected %s, got %s", tc.out, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package op
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseExpr parses a mathematical expression and returns the corresponding
+// Operator. It accepts the infix notation produced by the String method of
+// each Operator, for example "x0*cos(x1)+3.2", as well as the notation
+// produced by CodeDisplay, for example "add(mul(x0, cos(x1)), 3.2)". Both
+// notations can be mixed. Functions are called by their name; the built-in
+// functions and the ones added with Register are available. The usual
+// precedence rules apply: powers ("^" and "²") bind tighter than the unary
+// minus, which itself binds tighter than products and sums. Because Inv and a
+// Div whose numerator is 1 have the same infix notation, "1/x0" is parsed as
+// Inv{Var{0}}; both evaluate to the same values.
+func ParseExpr(expr string) (Operator, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	var p = &exprParser{tokens: tokens}
+	op, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.unexpected(tok)
+	}
+	return op, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize splits an expression into numbers, identifiers and symbols.
+func tokenize(expr string) ([]token, error) {
+	var (
+		tokens = make([]token, 0)
+		runes  = []rune(expr)
+	)
+	for i := 0; i < len(runes); {
+		var r = runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			var j = i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			// Scientific notation
+			if j < len(runes) && (runes[j] == 'e' || runes[j] == 'E') {
+				var k = j + 1
+				if k < len(runes) && (runes[k] == '+' || runes[k] == '-') {
+					k++
+				}
+				if k < len(runes) && unicode.IsDigit(runes[k]) {
+					for k < len(runes) && unicode.IsDigit(runes[k]) {
+						k++
+					}
+					j = k
+				}
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[i:j]), i})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			var j = i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, string(runes[i:j]), i})
+			i = j
+		case strings.ContainsRune("+-*/^²|(),<", r):
+			tokens = append(tokens, token{tokSymbol, string(r), i})
+			i++
+		default:
+			return nil, fmt.Errorf("Unexpected character '%c' at position %d", r, i)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(runes)}), nil
+}
+
+// exprParser is a recursive descent parser. Each method parses one level of
+// precedence.
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	var tok = p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) isSymbol(s string) bool {
+	var tok = p.peek()
+	return tok.kind == tokSymbol && tok.text == s
+}
+
+func (p *exprParser) unexpected(tok token) error {
+	if tok.kind == tokEOF {
+		return fmt.Errorf("Unexpected end of expression at position %d", tok.pos)
+	}
+	return fmt.Errorf("Unexpected '%s' at position %d", tok.text, tok.pos)
+}
+
+func (p *exprParser) expectSymbol(s string) error {
+	if !p.isSymbol(s) {
+		return p.unexpected(p.peek())
+	}
+	p.next()
+	return nil
+}
+
+func (p *exprParser) expectIdent(s string) error {
+	if tok := p.peek(); tok.kind != tokIdent || tok.text != s {
+		return p.unexpected(tok)
+	}
+	p.next()
+	return nil
+}
+
+// parseSum parses a sequence of terms separated by "+" or "-".
+func (p *exprParser) parseSum() (Operator, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.isSymbol("+") || p.isSymbol("-") {
+		var symbol = p.next().text
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		if symbol == "+" {
+			left = Add{left, right}
+		} else {
+			left = Sub{left, right}
+		}
+	}
+	return left, nil
+}
+
+// parseProduct parses a sequence of factors separated by "*" or "/". A 1
+// divided by a factor is parsed as an Inv.
+func (p *exprParser) parseProduct() (Operator, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isSymbol("*") || p.isSymbol("/") {
+		var symbol = p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case symbol == "*":
+			left = Mul{left, right}
+		case left == Const{1}:
+			left = Inv{right}
+		default:
+			left = Div{left, right}
+		}
+	}
+	return left, nil
+}
+
+// parseUnary parses a unary minus. A minus directly followed by a number is
+// parsed as a negative constant, unless the number is raised to a power.
+func (p *exprParser) parseUnary() (Operator, error) {
+	if !p.isSymbol("-") {
+		return p.parsePower()
+	}
+	p.next()
+	if tok := p.peek(); tok.kind == tokNumber {
+		var after = p.tokens[p.pos+1]
+		if after.kind != tokSymbol || (after.text != "^" && after.text != "²") {
+			p.next()
+			val, err := strconv.ParseFloat(tok.text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid number '%s' at position %d", tok.text, tok.pos)
+			}
+			return Const{-val}, nil
+		}
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return Neg{operand}, nil
+}
+
+// parsePower parses a right-associative "^".
+func (p *exprParser) parsePower() (Operator, error) {
+	base, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isSymbol("^") {
+		return base, nil
+	}
+	p.next()
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return Pow{base, exponent}, nil
+}
+
+// parsePostfix parses a primary followed by any number of "²".
+func (p *exprParser) parsePostfix() (Operator, error) {
+	op, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isSymbol("²") {
+		p.next()
+		op = Square{op}
+	}
+	return op, nil
+}
+
+// parsePrimary parses a number, a variable, a function call, a parenthesized
+// expression or an absolute value.
+func (p *exprParser) parsePrimary() (Operator, error) {
+	var tok = p.next()
+	switch tok.kind {
+	case tokNumber:
+		val, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid number '%s' at position %d", tok.text, tok.pos)
+		}
+		return Const{val}, nil
+	case tokIdent:
+		if p.isSymbol("(") {
+			return p.parseCall(tok)
+		}
+		if idx, ok := parseVarName(tok.text); ok {
+			return Var{idx}, nil
+		}
+		return nil, fmt.Errorf("Unknown variable '%s' at position %d", tok.text, tok.pos)
+	case tokSymbol:
+		switch tok.text {
+		case "(":
+			op, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			return op, p.expectSymbol(")")
+		case "|":
+			op, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			return Abs{op}, p.expectSymbol("|")
+		}
+	}
+	return nil, p.unexpected(tok)
+}
+
+// parseCall parses the arguments of a function and sets them as the operands
+// of the function. The "if(a < 0 then b else c)" notation produced by
+// If.String is also handled.
+func (p *exprParser) parseCall(name token) (Operator, error) {
+	f, err := ParseFunc(name.text)
+	if err != nil {
+		return nil, fmt.Errorf("%s at position %d", err, name.pos)
+	}
+	p.next() // Skip "("
+	var args = make([]Operator, 0, f.Arity())
+	for {
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if _, ok := f.(If); ok && len(args) == 1 && p.isSymbol("<") {
+			if args, err = p.parseIfBranches(args); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if !p.isSymbol(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	if uint(len(args)) != f.Arity() {
+		return nil, fmt.Errorf("Function '%s' at position %d takes %d operands, got %d",
+			name.text, name.pos, f.Arity(), len(args))
+	}
+	for i, arg := range args {
+		f = f.SetOperand(uint(i), arg)
+	}
+	return f, nil
+}
+
+// parseIfBranches parses the "< 0 then b else c" part of an If.
+func (p *exprParser) parseIfBranches(args []Operator) ([]Operator, error) {
+	p.next() // Skip "<"
+	if tok := p.next(); tok.kind != tokNumber || tok.text != "0" {
+		return nil, p.unexpected(tok)
+	}
+	if err := p.expectIdent("then"); err != nil {
+		return nil, err
+	}
+	lower, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectIdent("else"); err != nil {
+		return nil, err
+	}
+	upper, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return append(args, lower, upper), nil
+}
+
+// parseVarName parses a variable name such as "x42".
+func parseVarName(name string) (uint, bool) {
+	if len(name) < 2 || name[0] != 'x' {
+		return 0, false
+	}
+	idx, err := strconv.ParseUint(name[1:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(idx), true
+}
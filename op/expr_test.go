@@ -0,0 +1,234 @@
+package op
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	var testCases = []struct {
+		in          string
+		out         Operator
+		raisesError bool
+	}{
+		{
+			in:  "x0*cos(x1) + 3.2",
+			out: Add{Mul{Var{0}, Cos{Var{1}}}, Const{3.2}},
+		},
+		{
+			in:  "add(mul(x0, cos(x1)), 3.2)",
+			out: Add{Mul{Var{0}, Cos{Var{1}}}, Const{3.2}},
+		},
+		{
+			in:  "x0-x1-x2",
+			out: Sub{Sub{Var{0}, Var{1}}, Var{2}},
+		},
+		{
+			in:  "x0-(x1-x2)",
+			out: Sub{Var{0}, Sub{Var{1}, Var{2}}},
+		},
+		{
+			in:  "x0+x1*x2",
+			out: Add{Var{0}, Mul{Var{1}, Var{2}}},
+		},
+		{
+			in:  "-x0*x1",
+			out: Mul{Neg{Var{0}}, Var{1}},
+		},
+		{
+			in:  "x0--2",
+			out: Sub{Var{0}, Const{-2}},
+		},
+		{
+			in:  "-2²",
+			out: Neg{Square{Const{2}}},
+		},
+		{
+			in:  "(-2)²",
+			out: Square{Const{-2}},
+		},
+		{
+			in:  "x0^x1^2",
+			out: Pow{Var{0}, Pow{Var{1}, Const{2}}},
+		},
+		{
+			in:  "x0^-1",
+			out: Pow{Var{0}, Const{-1}},
+		},
+		{
+			in:  "1/x0",
+			out: Inv{Var{0}},
+		},
+		{
+			in:  "1/x0/x1",
+			out: Div{Inv{Var{0}}, Var{1}},
+		},
+		{
+			in:  "x0/(1/(x1+x2))",
+			out: Div{Var{0}, Inv{Add{Var{1}, Var{2}}}},
+		},
+		{
+			in:  "2/x0",
+			out: Div{Const{2}, Var{0}},
+		},
+		{
+			in:  "div(1, x0)",
+			out: Div{Const{1}, Var{0}},
+		},
+		{
+			in:  "|x0-x1|*2",
+			out: Mul{Abs{Sub{Var{0}, Var{1}}}, Const{2}},
+		},
+		{
+			in:  "||x0||",
+			out: Abs{Abs{Var{0}}},
+		},
+		{
+			in:  "max(x0, 1e-3)",
+			out: Max{Var{0}, Const{0.001}},
+		},
+		{
+			in:  "if(x0 < 0 then x1 else 2)",
+			out: If{Var{0}, Var{1}, Const{2}},
+		},
+		{
+			in:  "if(x0, x1, 2)",
+			out: If{Var{0}, Var{1}, Const{2}},
+		},
+		{
+			in:          "x0+",
+			raisesError: true,
+		},
+		{
+			in:          "(x0+x1",
+			raisesError: true,
+		},
+		{
+			in:          "cos(x0, x1)",
+			raisesError: true,
+		},
+		{
+			in:          "foo(x0)",
+			raisesError: true,
+		},
+		{
+			in:          "y0",
+			raisesError: true,
+		},
+		{
+			in:          "x0 # 2",
+			raisesError: true,
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			out, err := ParseExpr(tc.in)
+			if (err != nil) != tc.raisesError {
+				t.Errorf("Expected error to be %t, got %v", tc.raisesError, err)
+				return
+			}
+			if out != tc.out {
+				t.Errorf("Expected %s, got %s", tc.out, out)
+			}
+		})
+	}
+}
+
+func TestParseExprRoundTrip(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{-1.5, 0.5, 2},
+			[]float64{2, -3, 0.25},
+			[]float64{0.5, 1, -2},
+		}
+		testCases = []Operator{
+			Add{Var{0}, Add{Var{1}, Const{42}}},
+			Sub{Var{0}, Add{Var{1}, Var{2}}},
+			Sub{Var{0}, Sub{Var{1}, Const{-1}}},
+			Mul{Var{0}, Div{Var{1}, Var{2}}},
+			Div{Var{0}, Inv{Var{1}}},
+			Mul{Var{0}, Inv{Add{Var{1}, Var{2}}}},
+			Neg{Neg{Const{2}}},
+			Neg{Mul{Var{0}, Var{1}}},
+			Square{Neg{Var{0}}},
+			Square{Const{-2}},
+			Neg{Square{Var{0}}},
+			Square{Square{Var{2}}},
+			Pow{Neg{Var{0}}, Const{2}},
+			Pow{Const{math.Copysign(0, -1)}, Var{0}},
+			Square{Const{math.Copysign(0, -1)}},
+			Pow{Pow{Var{0}, Var{1}}, Var{2}},
+			Pow{Var{0}, Inv{Var{1}}},
+			Abs{Sub{Var{0}, Var{1}}},
+			Cos{Sin{Mul{Var{0}, Const{-0.5}}}},
+			Exp{Log{Sqrt{Var{0}}}},
+			Tanh{Sigmoid{Var{1}}},
+			Max{Min{Var{0}, Const{1}}, Var{2}},
+			If{Sub{Var{0}, Var{1}}, Neg{Var{2}}, Const{3}},
+		}
+	)
+	for i, op := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			// Infix notation
+			parsed, err := ParseExpr(op.String())
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if parsed.String() != op.String() {
+				t.Errorf("Expected %s, got %s", op, parsed)
+			}
+			var (
+				y1 = op.Eval(X)
+				y2 = parsed.Eval(X)
+			)
+			for j := range y1 {
+				if math.Abs(y1[j]-y2[j]) > 10e-10 {
+					t.Errorf("%s: expected %.5f, got %.5f", op, y1[j], y2[j])
+				}
+			}
+			// Code notation
+			parsed, err = ParseExpr(CodeDisplay{}.Apply(op))
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if parsed != op {
+				t.Errorf("Expected %s, got %s", op, parsed)
+			}
+		})
+	}
+}
+
+func TestParseExprInv(t *testing.T) {
+	var X = [][]float64{[]float64{-2, 0, 0.5}}
+	// Inv is parsed back as itself
+	parsed, err := ParseExpr(Inv{Var{0}}.String())
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if parsed != (Inv{Var{0}}) {
+		t.Errorf("Expected %s, got %#v", Inv{Var{0}}, parsed)
+	}
+	// A Div whose numerator is 1 is parsed as an Inv, which has the same
+	// values
+	var div = Div{Const{1}, Var{0}}
+	if parsed, err = ParseExpr(div.String()); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if parsed != (Inv{Var{0}}) {
+		t.Errorf("Expected %s, got %#v", Inv{Var{0}}, parsed)
+	}
+	var (
+		y1 = div.Eval(X)
+		y2 = parsed.Eval(X)
+	)
+	for j := range y1 {
+		if y1[j] != y2[j] {
+			t.Errorf("Expected %.5f, got %.5f", y1[j], y2[j])
+		}
+	}
+}
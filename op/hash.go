@@ -0,0 +1,59 @@
+package op
+
+import "math"
+
+// Parameters of the 64 bit FNV-1a hash function.
+const (
+	fnvOffset uint64 = 14695981039346656037
+	fnvPrime  uint64 = 1099511628211
+)
+
+func hashByte(h uint64, b byte) uint64 {
+	return (h ^ uint64(b)) * fnvPrime
+}
+
+func hashUint64(h, x uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h = hashByte(h, byte(x))
+		x >>= 8
+	}
+	return h
+}
+
+func hashString(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h = hashByte(h, s[i])
+	}
+	return h
+}
+
+// hashNode returns the hash of an Operator given the hashes of it's operands.
+func hashNode(op Operator, operands []uint64) uint64 {
+	var h = fnvOffset
+	switch op := op.(type) {
+	case Const:
+		h = hashUint64(hashByte(h, 'c'), math.Float64bits(op.Value))
+	case Var:
+		h = hashUint64(hashByte(h, 'v'), uint64(op.Index))
+	default:
+		h = hashString(hashByte(h, 'f'), op.Name())
+		for _, oh := range operands {
+			h = hashUint64(h, oh)
+		}
+	}
+	return h
+}
+
+// Hash returns a structural hash of an Operator. Operators with the same
+// structure, the same Consts and the same Vars have the same hash. The hash of
+// an Operator is obtained from the hashes of it's operands, hence equal
+// subtrees always have equal hashes. Operators are not simplified beforehand,
+// which means Hash has to be applied to simplified Operators in order to
+// obtain a canonical hash.
+func Hash(op Operator) uint64 {
+	var operands = make([]uint64, op.Arity())
+	for i := range operands {
+		operands[i] = Hash(op.Operand(uint(i)))
+	}
+	return hashNode(op, operands)
+}
@@ -0,0 +1,29 @@
+package op
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	var testCases = []struct {
+		op1, op2 Operator
+		equal    bool
+	}{
+		{Add{Var{0}, Const{1}}, Add{Var{0}, Const{1}}, true},
+		{Add{Var{0}, Const{1}}, Add{Const{1}, Var{0}}, false},
+		{Add{Var{0}, Const{1}}, Sub{Var{0}, Const{1}}, false},
+		{Add{Var{0}, Const{1}}, Add{Var{0}, Const{2}}, false},
+		{Add{Var{0}, Const{1}}, Add{Var{1}, Const{1}}, false},
+		{Var{1}, Const{1}, false},
+		{Neg{Neg{Var{0}}}, Neg{Var{0}}, false},
+		{Add{Var{0}, Const{0}}.Simplify(), Var{0}, true},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			if (Hash(tc.op1) == Hash(tc.op2)) != tc.equal {
+				t.Errorf("Expected the hashes of %s and %s to be equal: %t", tc.op1, tc.op2, tc.equal)
+			}
+		})
+	}
+}
@@ -69,9 +69,10 @@ func (iff If) Simplify() Operator {
 	return iff
 }
 
-// Diff compute the following derivative: (1 / u)' = -u' / u².
+// Diff computes the following derivative: if(c, u, v)' = if(c, u', v'). The
+// condition is ignored because it only makes the derivative discontinuous.
 func (iff If) Diff(i uint) Operator {
-	return iff
+	return If{iff.Condition, iff.Lower.Diff(i), iff.Upper.Diff(i)}
 }
 
 // Name of If is "if".
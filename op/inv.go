@@ -60,7 +60,7 @@ func (inv Inv) Simplify() Operator {
 
 // Diff compute the following derivative: (1 / u)' = -u' / u².
 func (inv Inv) Diff(i uint) Operator {
-	return Inv{inv.Op.Diff(i)}
+	return Neg{Div{inv.Op.Diff(i), Square{inv.Op}}}
 }
 
 // Name of Inv is "inv".
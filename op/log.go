@@ -0,0 +1,82 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+// safeLog computes the natural logarithm of the absolute value of a number. 0
+// is returned if the number is 0.
+func safeLog(a float64) float64 {
+	if a == 0 {
+		return 0
+	}
+	return math.Log(math.Abs(a))
+}
+
+// The Log operator.
+type Log struct {
+	Op Operator
+}
+
+// Eval computes the protected natural logarithm of each value.
+func (log Log) Eval(X [][]float64) []float64 {
+	x := log.Op.Eval(X)
+	for i, xi := range x {
+		x[i] = safeLog(xi)
+	}
+	return x
+}
+
+// Arity of Log is 1.
+func (log Log) Arity() uint {
+	return 1
+}
+
+// Operand returns Log's operand or nil.
+func (log Log) Operand(i uint) Operator {
+	if i == 0 {
+		return log.Op
+	}
+	return nil
+}
+
+// SetOperand replaces Log's operand if i is equal to 0.
+func (log Log) SetOperand(i uint, op Operator) Operator {
+	if i == 0 {
+		log.Op = op
+	}
+	return log
+}
+
+// Simplify Log.
+func (log Log) Simplify() Operator {
+	log.Op = log.Op.Simplify()
+	switch op := log.Op.(type) {
+	// log(a) = b
+	case Const:
+		return Const{safeLog(op.Value)}
+	// log(|x|) = log(x)
+	case Abs:
+		return Log{op.Op}.Simplify()
+	// log(-x) = log(x)
+	case Neg:
+		return Log{op.Op}.Simplify()
+	}
+	return log
+}
+
+// Diff computes the following derivative: log(|u|)' = u' / u
+func (log Log) Diff(i uint) Operator {
+	return Div{log.Op.Diff(i), log.Op}
+}
+
+// Name of Log is "log".
+func (log Log) Name() string {
+	return "log"
+}
+
+// String formatting.
+func (log Log) String() string {
+	return fmt.Sprintf("log(%s)", log.Op)
+}
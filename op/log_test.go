@@ -0,0 +1,42 @@
+package op
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLogSimplify(t *testing.T) {
+	var testCases = []struct {
+		in  Log
+		out Operator
+	}{
+		{
+			in:  Log{Mul{Add{Const{1}, Const{2}}, Var{0}}},
+			out: Log{Mul{Const{3}, Var{0}}},
+		},
+		{
+			in:  Log{Const{1}},
+			out: Const{0},
+		},
+		{
+			in:  Log{Const{0}},
+			out: Const{0},
+		},
+		{
+			in:  Log{Abs{Neg{Var{0}}}},
+			out: Log{Var{0}},
+		},
+		{
+			in:  Log{Var{0}},
+			out: Log{Var{0}},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			out := tc.in.Simplify()
+			if out != tc.out {
+				t.Errorf("Expected %s, got %s", tc.out, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package op
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Normalize rewrites an Operator into a canonical form, which goes further
+// than Simplify because the latter only applies local rules. Sums and
+// products are flattened across the whole tree, their operands are sorted,
+// like terms are collected and the constants are folded together. For
+// example x0+2+x0-1 becomes 2*x0+1 and (x0*3)*2 becomes 6*x0. Operators which
+// are neither sums nor products have their operands normalized and are then
+// simplified. Like Simplify, Normalize relies on the rules of real arithmetic,
+// hence the outputs might differ in case of infinite values or rounding
+// errors.
+func Normalize(op Operator) Operator {
+	return newLinear(op).operator()
+}
+
+// A term is a non-constant Operator multiplied by a coefficient.
+type term struct {
+	coeff float64
+	op    Operator
+}
+
+// A linear is a sum of terms plus a constant. Operators are converted to
+// linears in order to collect like terms and fold the constants.
+type linear struct {
+	constant float64
+	terms    []term
+}
+
+// newLinear converts a normalized version of an Operator to a linear.
+func newLinear(op Operator) linear {
+	switch op := op.(type) {
+	case Const:
+		return linear{constant: op.Value}
+	case Var:
+		return linear{terms: []term{{1, op}}}
+	case Add:
+		return newLinear(op.Left).add(newLinear(op.Right), 1)
+	case Sub:
+		return newLinear(op.Left).add(newLinear(op.Right), -1)
+	case Neg:
+		return newLinear(op.Op).scale(-1)
+	case Mul:
+		return newLinear(op.Left).mul(newLinear(op.Right))
+	case Square:
+		var operand = newLinear(op.Op)
+		return operand.mul(operand)
+	case Div:
+		// Dividing by a non-zero constant is the same as multiplying by it's
+		// inverse
+		var right = newLinear(op.Right)
+		if right.isConst() && right.constant != 0 {
+			return newLinear(op.Left).scale(1 / right.constant)
+		}
+		return newLinearOpaque(Div{Left: Normalize(op.Left), Right: right.operator()})
+	}
+	for i := uint(0); i < op.Arity(); i++ {
+		op = op.SetOperand(i, Normalize(op.Operand(i)))
+	}
+	return newLinearOpaque(op)
+}
+
+// newLinearOpaque converts an Operator which is neither a sum nor a product and
+// whose operands have been normalized. It is simplified beforehand in case a
+// local rule applies.
+func newLinearOpaque(op Operator) linear {
+	// The simplification might turn the Operator into a constant, a sum or a
+	// product, such as x/-1 which becomes -x
+	var simple = op.Simplify()
+	switch simple.(type) {
+	case Const, Add, Sub, Neg, Mul:
+		return newLinear(simple)
+	}
+	return linear{terms: []term{{1, simple}}}
+}
+
+func (l linear) isConst() bool {
+	return len(l.terms) == 0
+}
+
+// add returns l + coeff * m, like terms are collected and the terms whose
+// coefficient is 0 are removed.
+func (l linear) add(m linear, coeff float64) linear {
+	var sum = linear{
+		constant: l.constant + coeff*m.constant,
+		terms:    make([]term, 0, len(l.terms)+len(m.terms)),
+	}
+	sum.terms = append(sum.terms, l.terms...)
+	for _, t := range m.terms {
+		var found bool
+		for i, s := range sum.terms {
+			if compareOps(s.op, t.op) == 0 {
+				sum.terms[i].coeff += coeff * t.coeff
+				found = true
+				break
+			}
+		}
+		if !found {
+			sum.terms = append(sum.terms, term{coeff * t.coeff, t.op})
+		}
+	}
+	var terms = sum.terms[:0]
+	for _, t := range sum.terms {
+		if t.coeff != 0 {
+			terms = append(terms, t)
+		}
+	}
+	sum.terms = terms
+	return sum
+}
+
+// scale returns coeff * l.
+func (l linear) scale(coeff float64) linear {
+	return linear{}.add(l, coeff)
+}
+
+// mul returns l * m. A constant is distributed over the terms of the other
+// linear, otherwise the product of both linears becomes a single term.
+func (l linear) mul(m linear) linear {
+	if l.isConst() {
+		return m.scale(l.constant)
+	}
+	if m.isConst() {
+		return l.scale(m.constant)
+	}
+	var (
+		lCoeff, lFactors = l.factors()
+		mCoeff, mFactors = m.factors()
+	)
+	return linear{terms: []term{{lCoeff * mCoeff, newProduct(append(lFactors, mFactors...))}}}
+}
+
+// factors returns the coefficient and the factors of a linear which is used
+// as an operand of a product. A linear with more than one term or with a
+// constant is a single factor.
+func (l linear) factors() (float64, []Operator) {
+	if l.constant != 0 || len(l.terms) != 1 {
+		return 1, []Operator{l.operator()}
+	}
+	return l.terms[0].coeff, flattenProduct(l.terms[0].op)
+}
+
+// flattenProduct returns the factors of a product, squares counting as two
+// factors.
+func flattenProduct(op Operator) []Operator {
+	switch op := op.(type) {
+	case Mul:
+		return append(flattenProduct(op.Left), flattenProduct(op.Right)...)
+	case Square:
+		var factors = flattenProduct(op.Op)
+		return append(factors, factors...)
+	}
+	return []Operator{op}
+}
+
+// newProduct sorts factors and multiplies them together, pairs of identical
+// factors being replaced with squares.
+func newProduct(factors []Operator) Operator {
+	sortOps(factors)
+	var product Operator
+	for i := 0; i < len(factors); i++ {
+		var factor = factors[i]
+		if i+1 < len(factors) && compareOps(factor, factors[i+1]) == 0 {
+			factor = Square{factor}
+			i++
+		}
+		if product == nil {
+			product = factor
+		} else {
+			product = Mul{product, factor}
+		}
+	}
+	return product
+}
+
+// operator converts a linear back to an Operator. The terms are sorted and
+// the constant comes last.
+func (l linear) operator() Operator {
+	var terms = make([]term, len(l.terms))
+	copy(terms, l.terms)
+	sort.SliceStable(terms, func(i, j int) bool { return compareOps(terms[i].op, terms[j].op) < 0 })
+	var sum Operator
+	for _, t := range terms {
+		var (
+			coeff = math.Abs(t.coeff)
+			op    = t.op
+		)
+		if coeff != 1 {
+			op = Mul{Const{coeff}, op}
+		}
+		switch {
+		case sum == nil && t.coeff < 0:
+			sum = Neg{op}
+		case sum == nil:
+			sum = op
+		case t.coeff < 0:
+			sum = Sub{sum, op}
+		default:
+			sum = Add{sum, op}
+		}
+	}
+	switch {
+	case sum == nil:
+		return Const{l.constant}
+	case l.constant < 0:
+		return Sub{sum, Const{-l.constant}}
+	case l.constant > 0:
+		return Add{sum, Const{l.constant}}
+	}
+	return sum
+}
+
+// sortOps sorts Operators in place according to compareOps.
+func sortOps(ops []Operator) {
+	sort.SliceStable(ops, func(i, j int) bool { return compareOps(ops[i], ops[j]) < 0 })
+}
+
+// compareOps defines a total order on Operators, it returns 0 if both
+// Operators are identical. Leaves come before functions, Vars being ordered by
+// index, and functions are ordered by name and then by operands.
+func compareOps(a, b Operator) int {
+	if a.Arity() != b.Arity() {
+		if a.Arity() < b.Arity() {
+			return -1
+		}
+		return 1
+	}
+	if c := strings.Compare(a.Name(), b.Name()); c != 0 {
+		// Vars come before Consts
+		if _, ok := a.(Var); ok {
+			if _, ok := b.(Var); !ok {
+				return -1
+			}
+		} else if _, ok := b.(Var); ok {
+			return 1
+		}
+		if a, ok := a.(Var); ok {
+			if a.Index < b.(Var).Index {
+				return -1
+			}
+			return 1
+		}
+		return c
+	}
+	for i := uint(0); i < a.Arity(); i++ {
+		if c := compareOps(a.Operand(i), b.Operand(i)); c != 0 {
+			return c
+		}
+	}
+	// Leaves which are neither Vars nor Consts might have the same name but
+	// different parameters
+	if a.Arity() == 0 {
+		return strings.Compare(a.String(), b.String())
+	}
+	return 0
+}
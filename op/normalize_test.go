@@ -0,0 +1,126 @@
+package op
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	var testCases = []struct {
+		in  Operator
+		out string
+	}{
+		{
+			in:  Sub{Add{Add{Var{0}, Const{2}}, Var{0}}, Const{1}},
+			out: "2*x0+1",
+		},
+		{
+			in:  Mul{Mul{Var{0}, Const{3}}, Const{2}},
+			out: "6*x0",
+		},
+		{
+			in:  Add{Var{1}, Var{0}},
+			out: "x0+x1",
+		},
+		{
+			in:  Sub{Add{Var{0}, Var{1}}, Add{Var{1}, Var{0}}},
+			out: "0",
+		},
+		{
+			in:  Mul{Const{2}, Sub{Var{0}, Const{1}}},
+			out: "2*x0-2",
+		},
+		{
+			in:  Div{Add{Var{0}, Var{0}}, Const{4}},
+			out: "0.5*x0",
+		},
+		{
+			in:  Mul{Neg{Var{1}}, Mul{Var{0}, Var{1}}},
+			out: "-(x0*x1²)",
+		},
+		{
+			in:  Add{Square{Mul{Const{3}, Var{2}}}, Mul{Var{2}, Var{2}}},
+			out: "10*x2²",
+		},
+		{
+			in:  Add{Cos{Add{Var{1}, Var{0}}}, Cos{Add{Var{0}, Var{1}}}},
+			out: "2*cos(x0+x1)",
+		},
+		{
+			in:  Div{Var{0}, Sub{Var{1}, Var{1}}},
+			out: "1",
+		},
+		{
+			in:  Sub{Const{1}, Mul{Var{0}, Add{Var{1}, Const{1}}}},
+			out: "-(x0*(x1+1))+1",
+		},
+		{
+			in:  Max{Add{Var{1}, Const{2}}, Sub{Var{0}, Var{0}}},
+			out: "max(x1+2, 0)",
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var out = Normalize(tc.in)
+			if out.String() != tc.out {
+				t.Errorf("Expected %s, got %s", tc.out, out)
+			}
+		})
+	}
+}
+
+// randArithmeticOp generates a random Operator whose outputs don't depend too
+// much on rounding errors.
+func randArithmeticOp(height int, nFeatures int, rng *rand.Rand) Operator {
+	if height == 0 || rng.Float64() < 0.2 {
+		if rng.Float64() < 0.3 {
+			return Const{[]float64{-1, 0, 0.5, 2, 3}[rng.Intn(5)]}
+		}
+		return Var{uint(rng.Intn(nFeatures))}
+	}
+	var funcs = []Operator{Add{}, Sub{}, Mul{}, Neg{}, Square{}, Cos{}, Div{Right: Const{4}}}
+	var op = funcs[rng.Intn(len(funcs))]
+	for i := uint(0); i < op.Arity(); i++ {
+		if _, ok := op.Operand(i).(Const); ok {
+			continue
+		}
+		op = op.SetOperand(i, randArithmeticOp(height-1, nFeatures, rng))
+	}
+	return op
+}
+
+func TestNormalizeRandom(t *testing.T) {
+	var (
+		rng = rand.New(rand.NewSource(42))
+		X   = make([][]float64, 3)
+	)
+	for j := range X {
+		X[j] = make([]float64, 20)
+		for i := range X[j] {
+			X[j][i] = 2*rng.Float64() - 1
+		}
+	}
+	for i := 0; i < 500; i++ {
+		var op = randArithmeticOp(5, len(X), rng)
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var norm = Normalize(op)
+			// The outputs have to be the same
+			var (
+				expected = op.Eval(X)
+				y        = norm.Eval(X)
+			)
+			for j := range y {
+				if math.Abs(y[j]-expected[j]) > 1e-9*math.Max(1, math.Abs(expected[j])) {
+					t.Errorf("%s -> %s: expected %v at row %d, got %v", op, norm, expected[j], j, y[j])
+					return
+				}
+			}
+			// Normalizing twice has to give the same Operator
+			if again := Normalize(norm); again != norm {
+				t.Errorf("%s -> %s: expected %s, got %s", op, norm, norm, again)
+			}
+		})
+	}
+}
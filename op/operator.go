@@ -226,6 +226,38 @@ func SetConsts(op Operator, values []float64) Operator {
 	return op
 }
 
+// ConstsToVars replaces each Const with a Var. The Index of the first Var is
+// offset and is incremented for each Const. Pre-order traversal is used.
+func ConstsToVars(op Operator, offset uint) Operator {
+	var counter uint
+	return Replace(
+		op,
+		func(op Operator) bool { _, ok := op.(Const); return ok },
+		func(op Operator) Operator {
+			defer func() { counter++ }()
+			return Var{offset + counter}
+		},
+		false,
+	)
+}
+
+// DiffConsts returns the derivatives of an Operator with respect to each of
+// it's Consts, ordered in the same way as GetConsts. The Consts are replaced
+// with Vars by ConstsToVars, hence the derivatives are evaluated by appending
+// a column for each Const to the features. The Operator where the Consts
+// have been replaced is also returned so that it can be evaluated the same
+// way.
+func DiffConsts(op Operator, offset uint) (Operator, []Operator) {
+	var (
+		param = ConstsToVars(op, offset)
+		diffs = make([]Operator, CountConsts(op))
+	)
+	for i := range diffs {
+		diffs[i] = param.Diff(offset + uint(i)).Simplify()
+	}
+	return param, diffs
+}
+
 // GetVars returns the Vars contained in an Operator. Pre-order traversal is
 // used.
 func GetVars(op Operator) []uint {
@@ -43,11 +43,21 @@ func TestEval(t *testing.T) {
 			op:  Div{Var{0}, Var{1}},
 			out: []float64{0, 1, 2},
 		},
+		{
+			in:  [][]float64{[]float64{0, 1}},
+			op:  Exp{Var{0}},
+			out: []float64{1, math.E},
+		},
 		{
 			in:  [][]float64{[]float64{0, 1, 2}},
 			op:  Inv{Var{0}},
 			out: []float64{1, 1, 0.5},
 		},
+		{
+			in:  [][]float64{[]float64{0, 1, -math.E}},
+			op:  Log{Var{0}},
+			out: []float64{0, 0, 1},
+		},
 		{
 			in:  [][]float64{[]float64{0, 1, 2}},
 			op:  Max{Var{0}, Const{1}},
@@ -68,11 +78,29 @@ func TestEval(t *testing.T) {
 			op:  Neg{Var{0}},
 			out: []float64{0, -1, -2},
 		},
+		{
+			in: [][]float64{
+				[]float64{2, -2, 0, 3},
+				[]float64{3, 0.5, -1, 0},
+			},
+			op:  Pow{Var{0}, Var{1}},
+			out: []float64{8, 1, 1, 1},
+		},
+		{
+			in:  [][]float64{[]float64{0}},
+			op:  Sigmoid{Var{0}},
+			out: []float64{0.5},
+		},
 		{
 			in:  [][]float64{[]float64{0, 0.5 * math.Pi}},
 			op:  Sin{Var{0}},
 			out: []float64{0, 1},
 		},
+		{
+			in:  [][]float64{[]float64{-4, 0, 9}},
+			op:  Sqrt{Var{0}},
+			out: []float64{2, 0, 3},
+		},
 		{
 			in:  [][]float64{[]float64{-2, 1, 2}},
 			op:  Square{Var{0}},
@@ -83,6 +111,11 @@ func TestEval(t *testing.T) {
 			op:  Sub{Var{0}, Const{-1}},
 			out: []float64{1, 2, 3},
 		},
+		{
+			in:  [][]float64{[]float64{0}},
+			op:  Tanh{Var{0}},
+			out: []float64{0},
+		},
 	}
 	for i, tc := range testCases {
 		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
@@ -105,12 +138,18 @@ func TestArity(t *testing.T) {
 		{Add{}, 2},
 		{Cos{}, 1},
 		{Div{}, 2},
+		{Exp{}, 1},
 		{Inv{}, 1},
+		{Log{}, 1},
 		{Max{}, 2},
 		{Min{}, 2},
+		{Pow{}, 2},
+		{Sigmoid{}, 1},
 		{Sin{}, 1},
+		{Sqrt{}, 1},
 		{Square{}, 1},
 		{Sub{}, 2},
+		{Tanh{}, 1},
 	}
 	for i, tc := range testCases {
 		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
@@ -175,6 +214,42 @@ func TestString(t *testing.T) {
 			in:  Sub{Max{Var{0}, Var{1}}, Var{2}},
 			out: "max(x0, x1)-x2",
 		},
+		{
+			in:  Sub{Var{0}, Add{Var{1}, Var{2}}},
+			out: "x0-(x1+x2)",
+		},
+		{
+			in:  Square{Neg{Var{0}}},
+			out: "(-x0)²",
+		},
+		{
+			in:  Div{Var{0}, Inv{Var{1}}},
+			out: "x0/(1/x1)",
+		},
+		{
+			in:  Exp{Add{Var{0}, Var{1}}},
+			out: "exp(x0+x1)",
+		},
+		{
+			in:  Log{Var{0}},
+			out: "log(x0)",
+		},
+		{
+			in:  Pow{Add{Var{0}, Var{1}}, Const{3}},
+			out: "(x0+x1)^3",
+		},
+		{
+			in:  Sigmoid{Var{0}},
+			out: "sigmoid(x0)",
+		},
+		{
+			in:  Sqrt{Var{0}},
+			out: "sqrt(x0)",
+		},
+		{
+			in:  Tanh{Var{0}},
+			out: "tanh(x0)",
+		},
 	}
 	for i, tc := range testCases {
 		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
@@ -434,3 +509,127 @@ func TestSetConsts(t *testing.T) {
 		})
 	}
 }
+
+// numDiff approximates the derivative of an Operator with respect to a Var
+// with central differences.
+func numDiff(op Operator, X [][]float64, i uint) []float64 {
+	const h = 1e-6
+	var shift = func(delta float64) [][]float64 {
+		var Xs = make([][]float64, len(X))
+		copy(Xs, X)
+		Xs[i] = make([]float64, len(X[i]))
+		for j, x := range X[i] {
+			Xs[i][j] = x + delta
+		}
+		return Xs
+	}
+	var (
+		up   = op.Eval(shift(h))
+		down = op.Eval(shift(-h))
+	)
+	for j := range up {
+		up[j] = (up[j] - down[j]) / (2 * h)
+	}
+	return up
+}
+
+func TestDiff(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{0.5, 1.3, 2.1},
+			[]float64{1.7, -0.8, 3.2},
+		}
+		testCases = []Operator{
+			Abs{Sub{Var{0}, Var{1}}},
+			Add{Var{0}, Mul{Var{0}, Var{1}}},
+			Cos{Var{0}},
+			Div{Var{0}, Var{1}},
+			Div{Var{1}, Add{Var{0}, Const{1}}},
+			Exp{Var{0}},
+			If{Sub{Var{0}, Const{1}}, Square{Var{0}}, Sin{Var{0}}},
+			Inv{Var{0}},
+			Inv{Mul{Var{0}, Var{1}}},
+			Log{Var{0}},
+			Max{Var{0}, Var{1}},
+			Min{Var{0}, Var{1}},
+			Neg{Var{0}},
+			Pow{Var{0}, Var{1}},
+			Sigmoid{Var{0}},
+			Sqrt{Var{0}},
+			Square{Var{0}},
+			Sub{Var{1}, Var{0}},
+			Tanh{Var{0}},
+		}
+	)
+	for i, op := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var (
+				got      = op.Diff(0).Eval(X)
+				expected = numDiff(op, X, 0)
+			)
+			for j := range got {
+				if math.Abs(got[j]-expected[j]) > 1e-4*math.Max(1, math.Abs(expected[j])) {
+					t.Errorf("Expected %.5f, got %.5f for %s", expected[j], got[j], op)
+				}
+			}
+		})
+	}
+}
+
+func TestConstsToVars(t *testing.T) {
+	var testCases = []struct {
+		in     Operator
+		offset uint
+		out    Operator
+	}{
+		{
+			in:     Const{42},
+			offset: 2,
+			out:    Var{2},
+		},
+		{
+			in:     Add{Mul{Const{1}, Var{0}}, Const{2}},
+			offset: 1,
+			out:    Add{Mul{Var{1}, Var{0}}, Var{2}},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			out := ConstsToVars(tc.in, tc.offset)
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Errorf("Expected %v, got %v", tc.out, out)
+			}
+		})
+	}
+}
+
+func TestDiffConsts(t *testing.T) {
+	var (
+		op     = Add{Mul{Const{3}, Exp{Mul{Const{-0.5}, Var{0}}}}, Div{Var{0}, Const{2}}}
+		consts = GetConsts(op)
+		X      = [][]float64{[]float64{0.5, 1.3, 2.1}}
+		XC     = append([][]float64{}, X...)
+	)
+	for _, c := range consts {
+		XC = append(XC, []float64{c, c, c})
+	}
+	param, diffs := DiffConsts(op, 1)
+	if !reflect.DeepEqual(param.Eval(XC), op.Eval(X)) {
+		t.Errorf("Expected %v, got %v", op.Eval(X), param.Eval(XC))
+	}
+	if len(diffs) != len(consts) {
+		t.Errorf("Expected %d derivatives, got %d", len(consts), len(diffs))
+		return
+	}
+	for i, diff := range diffs {
+		var (
+			got      = diff.Eval(XC)
+			expected = numDiff(param, XC, uint(1+i))
+		)
+		for j := range got {
+			if math.Abs(got[j]-expected[j]) > 1e-4*math.Max(1, math.Abs(expected[j])) {
+				t.Errorf("Expected %.5f, got %.5f for constant %d", expected[j], got[j], i)
+			}
+		}
+	}
+}
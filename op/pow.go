@@ -0,0 +1,200 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+// safePow raises a to the power of b. 1 is returned if the result is not a
+// finite number, for example when a negative number is raised to a
+// non-integer power.
+func safePow(a, b float64) float64 {
+	var p = math.Pow(a, b)
+	if math.IsNaN(p) || math.IsInf(p, 0) {
+		return 1
+	}
+	return p
+}
+
+// The Pow operator.
+type Pow struct {
+	Left, Right Operator
+}
+
+// Eval raises aligned values to the power of each other.
+func (pow Pow) Eval(X [][]float64) []float64 {
+	x := pow.Left.Eval(X)
+	y := pow.Right.Eval(X)
+	for i, yi := range y {
+		x[i] = safePow(x[i], yi)
+	}
+	return x
+}
+
+// Arity of Pow is 2.
+func (pow Pow) Arity() uint {
+	return 2
+}
+
+// Operand returns one of Pow's operands, or nil.
+func (pow Pow) Operand(i uint) Operator {
+	switch i {
+	case 0:
+		return pow.Left
+	case 1:
+		return pow.Right
+	default:
+		return nil
+	}
+}
+
+// SetOperand replaces one of Pow's operands if i is equal to 0 or 1.
+func (pow Pow) SetOperand(i uint, op Operator) Operator {
+	switch i {
+	case 0:
+		return Pow{op, pow.Right}
+	case 1:
+		return Pow{pow.Left, op}
+	default:
+		return pow
+	}
+}
+
+// Simplify Pow.
+func (pow Pow) Simplify() Operator {
+
+	// Simplify branches
+	pow.Left = pow.Left.Simplify()
+	pow.Right = pow.Right.Simplify()
+
+	switch right := pow.Right.(type) {
+	case Const:
+		switch right.Value {
+		// x ^ 0 = 1
+		case 0:
+			return Const{1}
+		// x ^ 1 = x
+		case 1:
+			return pow.Left
+		}
+		switch left := pow.Left.(type) {
+		// a ^ b = c
+		case Const:
+			return Const{safePow(left.Value, right.Value)}
+		}
+	}
+	switch left := pow.Left.(type) {
+	case Const:
+		switch left.Value {
+		// 1 ^ x = 1
+		case 1:
+			return Const{1}
+		}
+	}
+	return pow
+}
+
+// Diff computes the following derivative: (u ^ v)' = u ^ v * (v' * log(u) + vu' / u)
+// The derivative is 0 wherever safePow returns 1 because u ^ v isn't a finite
+// number, for example when u is negative and v isn't an integer.
+func (pow Pow) Diff(i uint) Operator {
+	return If{
+		powDefined{pow.Left, pow.Right},
+		Const{0},
+		Mul{
+			pow,
+			Add{
+				Mul{pow.Right.Diff(i), Log{pow.Left}},
+				Div{Mul{pow.Right, pow.Left.Diff(i)}, pow.Left},
+			},
+		},
+	}
+}
+
+// Name of Pow is "pow".
+func (pow Pow) Name() string {
+	return "pow"
+}
+
+// String formatting.
+func (pow Pow) String() string {
+	return fmt.Sprintf("%s^%s", parenthesizeBase(pow.Left), parenthesize(pow.Right))
+}
+
+// powDefined is 1 where Left raised to the power of Right is a finite number
+// and -1 otherwise, which is where safePow returns 1. It is only used as the
+// condition of the derivative of Pow.
+type powDefined struct {
+	Left, Right Operator
+}
+
+// Eval indicates where aligned values can be raised to the power of each
+// other.
+func (pd powDefined) Eval(X [][]float64) []float64 {
+	x := pd.Left.Eval(X)
+	y := pd.Right.Eval(X)
+	for i, yi := range y {
+		if p := math.Pow(x[i], yi); math.IsNaN(p) || math.IsInf(p, 0) {
+			x[i] = -1
+		} else {
+			x[i] = 1
+		}
+	}
+	return x
+}
+
+// Arity of powDefined is 2.
+func (pd powDefined) Arity() uint {
+	return 2
+}
+
+// Operand returns one of powDefined's operands, or nil.
+func (pd powDefined) Operand(i uint) Operator {
+	switch i {
+	case 0:
+		return pd.Left
+	case 1:
+		return pd.Right
+	default:
+		return nil
+	}
+}
+
+// SetOperand replaces one of powDefined's operands if i is equal to 0 or 1.
+func (pd powDefined) SetOperand(i uint, op Operator) Operator {
+	switch i {
+	case 0:
+		return powDefined{op, pd.Right}
+	case 1:
+		return powDefined{pd.Left, op}
+	default:
+		return pd
+	}
+}
+
+// Simplify powDefined.
+func (pd powDefined) Simplify() Operator {
+	pd.Left = pd.Left.Simplify()
+	pd.Right = pd.Right.Simplify()
+	if left, ok := pd.Left.(Const); ok {
+		if right, ok := pd.Right.(Const); ok {
+			return Const{pd.Eval([][]float64{{left.Value}, {right.Value}})[0]}
+		}
+	}
+	return pd
+}
+
+// Diff of powDefined is 0 because it is piecewise constant.
+func (pd powDefined) Diff(i uint) Operator {
+	return Const{0}
+}
+
+// Name of powDefined is "pow_defined".
+func (pd powDefined) Name() string {
+	return "pow_defined"
+}
+
+// String formatting.
+func (pd powDefined) String() string {
+	return fmt.Sprintf("pow_defined(%s, %s)", pd.Left, pd.Right)
+}
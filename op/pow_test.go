@@ -0,0 +1,106 @@
+package op
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestPowSimplify(t *testing.T) {
+	var testCases = []struct {
+		in  Pow
+		out Operator
+	}{
+		{
+			in:  Pow{Var{0}, Add{Const{1}, Const{2}}},
+			out: Pow{Var{0}, Const{3}},
+		},
+		{
+			in:  Pow{Const{2}, Const{3}},
+			out: Const{8},
+		},
+		{
+			in:  Pow{Const{-2}, Const{0.5}},
+			out: Const{1},
+		},
+		{
+			in:  Pow{Var{0}, Const{0}},
+			out: Const{1},
+		},
+		{
+			in:  Pow{Var{0}, Const{1}},
+			out: Var{0},
+		},
+		{
+			in:  Pow{Const{1}, Var{0}},
+			out: Const{1},
+		},
+		{
+			in:  Pow{Var{0}, Var{1}},
+			out: Pow{Var{0}, Var{1}},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			out := tc.in.Simplify()
+			if out != tc.out {
+				t.Errorf("Expected %s, got %s", tc.out, out)
+			}
+		})
+	}
+}
+
+func TestPowDiff(t *testing.T) {
+	var testCases = []struct {
+		op       Pow
+		X        [][]float64
+		i        uint
+		expected []float64
+	}{
+		// A negative base can't be raised to a non-integer power
+		{
+			op:       Pow{Var{0}, Const{0.5}},
+			X:        [][]float64{[]float64{-4, 4}},
+			i:        0,
+			expected: []float64{0, 0.25},
+		},
+		{
+			op:       Pow{Const{-2}, Var{0}},
+			X:        [][]float64{[]float64{0.5, 2}},
+			i:        0,
+			expected: []float64{0, 4 * math.Log(2)},
+		},
+		{
+			op:       Pow{Var{0}, Var{1}},
+			X:        [][]float64{[]float64{-2, -2}, []float64{1.5, 2}},
+			i:        1,
+			expected: []float64{0, 4 * math.Log(2)},
+		},
+		// A negative base can be raised to an integer power
+		{
+			op:       Pow{Var{0}, Const{3}},
+			X:        [][]float64{[]float64{-2, 2}},
+			i:        0,
+			expected: []float64{12, 12},
+		},
+		// Overflows are clamped
+		{
+			op:       Pow{Var{0}, Const{1000}},
+			X:        [][]float64{[]float64{10, 1}},
+			i:        0,
+			expected: []float64{0, 1000},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			for _, diff := range []Operator{tc.op.Diff(tc.i), tc.op.Diff(tc.i).Simplify()} {
+				var got = diff.Eval(tc.X)
+				for j := range got {
+					if math.Abs(got[j]-tc.expected[j]) > 1e-10 {
+						t.Errorf("Expected %.5f, got %.5f", tc.expected[j], got[j])
+					}
+				}
+			}
+		})
+	}
+}
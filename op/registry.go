@@ -0,0 +1,62 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// registry maps function names to Operators. It is consulted by ParseFunc,
+// which is itself used by ParseFuncs and ParseOp.
+var registry = struct {
+	sync.RWMutex
+	funcs map[string]Operator
+}{
+	funcs: map[string]Operator{
+		If{}.Name():      If{},
+		Abs{}.Name():     Abs{},
+		Add{}.Name():     Add{},
+		Cos{}.Name():     Cos{},
+		Div{}.Name():     Div{},
+		Exp{}.Name():     Exp{},
+		Inv{}.Name():     Inv{},
+		Log{}.Name():     Log{},
+		Max{}.Name():     Max{},
+		Min{}.Name():     Min{},
+		Mul{}.Name():     Mul{},
+		Neg{}.Name():     Neg{},
+		Pow{}.Name():     Pow{},
+		Sigmoid{}.Name(): Sigmoid{},
+		Sin{}.Name():     Sin{},
+		Sqrt{}.Name():    Sqrt{},
+		Square{}.Name():  Square{},
+		Sub{}.Name():     Sub{},
+		Tanh{}.Name():    Tanh{},
+	},
+}
+
+// Register makes a user-defined function available under the name returned by
+// it's Name method. The function can then be used in GPConfig.Funcs and be
+// saved and loaded with SerializeOp and ParseOp. The given Operator should
+// have empty operands; it is used as a template whose operands are set with
+// SetOperand. Register returns an error if the name is already taken or if
+// the Operator has no operands.
+func Register(f Operator) error {
+	if f == nil {
+		return errors.New("Can't register a nil Operator")
+	}
+	if f.Arity() == 0 {
+		return fmt.Errorf("Can't register '%s' because it has no operands", f.Name())
+	}
+	var name = f.Name()
+	if name == "" {
+		return errors.New("Can't register an Operator with an empty name")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.funcs[name]; ok {
+		return fmt.Errorf("A function named '%s' is already registered", name)
+	}
+	registry.funcs[name] = f
+	return nil
+}
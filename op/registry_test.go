@@ -0,0 +1,111 @@
+package op
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// hill is a saturating Hill function with a half-saturation constant of 1 and
+// a Hill coefficient of 2.
+type hill struct {
+	Op Operator
+}
+
+func (h hill) Eval(X [][]float64) []float64 {
+	x := h.Op.Eval(X)
+	for i, xi := range x {
+		x[i] = math.Pow(xi, 2) / (1 + math.Pow(xi, 2))
+	}
+	return x
+}
+
+func (h hill) Arity() uint {
+	return 1
+}
+
+func (h hill) Operand(i uint) Operator {
+	if i == 0 {
+		return h.Op
+	}
+	return nil
+}
+
+func (h hill) SetOperand(i uint, op Operator) Operator {
+	if i == 0 {
+		h.Op = op
+	}
+	return h
+}
+
+func (h hill) Simplify() Operator {
+	h.Op = h.Op.Simplify()
+	return h
+}
+
+func (h hill) Diff(i uint) Operator {
+	return Div{Mul{Const{2}, Mul{h.Op, h.Op.Diff(i)}}, Square{Add{Const{1}, Square{h.Op}}}}
+}
+
+func (h hill) Name() string {
+	return "hill"
+}
+
+func (h hill) String() string {
+	return fmt.Sprintf("hill(%s)", h.Op)
+}
+
+func TestRegister(t *testing.T) {
+	var testCases = []struct {
+		f           Operator
+		raisesError bool
+	}{
+		{
+			f:           hill{},
+			raisesError: false,
+		},
+		{
+			f:           hill{},
+			raisesError: true,
+		},
+		{
+			f:           Add{},
+			raisesError: true,
+		},
+		{
+			f:           Const{42},
+			raisesError: true,
+		},
+		{
+			f:           nil,
+			raisesError: true,
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var err = Register(tc.f)
+			if (err != nil) != tc.raisesError {
+				t.Errorf("Expected error to be %t, got %v", tc.raisesError, err)
+			}
+		})
+	}
+
+	// The registered function can now be parsed and deserialized
+	funcs, err := ParseFuncs("add,hill", ",")
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if funcs[1] != (hill{}) {
+		t.Errorf("Expected hill, got %s", funcs[1].Name())
+	}
+	var op = Add{hill{Var{0}}, Const{1}}
+	parsed, err := ParseOp(SerializeOp(op))
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if parsed != op {
+		t.Errorf("Expected %s, got %s", op, parsed)
+	}
+}
@@ -6,23 +6,12 @@ import (
 	"strings"
 )
 
-// ParseFunc parses a name and returns the corresponding Operator.
+// ParseFunc parses a name and returns the corresponding Operator. Both the
+// built-in functions and the ones added with Register are available.
 func ParseFunc(name string) (Operator, error) {
-	var f, ok = map[string]Operator{
-		If{}.Name():     If{},
-		Abs{}.Name():    Abs{},
-		Add{}.Name():    Add{},
-		Cos{}.Name():    Cos{},
-		Div{}.Name():    Div{},
-		Inv{}.Name():    Inv{},
-		Max{}.Name():    Max{},
-		Min{}.Name():    Min{},
-		Mul{}.Name():    Mul{},
-		Neg{}.Name():    Neg{},
-		Sin{}.Name():    Sin{},
-		Square{}.Name(): Square{},
-		Sub{}.Name():    Sub{},
-	}[name]
+	registry.RLock()
+	var f, ok = registry.funcs[name]
+	registry.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("Unknown function name '%s'", name)
 	}
@@ -0,0 +1,71 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+// sigmoid computes the logistic function of a number.
+func sigmoid(a float64) float64 {
+	return 1 / (1 + math.Exp(-a))
+}
+
+// The Sigmoid operator.
+type Sigmoid struct {
+	Op Operator
+}
+
+// Eval computes the logistic function of each value.
+func (sig Sigmoid) Eval(X [][]float64) []float64 {
+	x := sig.Op.Eval(X)
+	for i, xi := range x {
+		x[i] = sigmoid(xi)
+	}
+	return x
+}
+
+// Arity of Sigmoid is 1.
+func (sig Sigmoid) Arity() uint {
+	return 1
+}
+
+// Operand returns Sigmoid's operand or nil.
+func (sig Sigmoid) Operand(i uint) Operator {
+	if i == 0 {
+		return sig.Op
+	}
+	return nil
+}
+
+// SetOperand replaces Sigmoid's operand if i is equal to 0.
+func (sig Sigmoid) SetOperand(i uint, op Operator) Operator {
+	if i == 0 {
+		sig.Op = op
+	}
+	return sig
+}
+
+// Simplify Sigmoid.
+func (sig Sigmoid) Simplify() Operator {
+	sig.Op = sig.Op.Simplify()
+	switch op := sig.Op.(type) {
+	case Const:
+		return Const{sigmoid(op.Value)}
+	}
+	return sig
+}
+
+// Diff computes the following derivative: sigmoid(u)' = u' * sigmoid(u) * (1 - sigmoid(u))
+func (sig Sigmoid) Diff(i uint) Operator {
+	return Mul{sig.Op.Diff(i), Mul{sig, Sub{Const{1}, sig}}}
+}
+
+// Name of Sigmoid is "sigmoid".
+func (sig Sigmoid) Name() string {
+	return "sigmoid"
+}
+
+// String formatting.
+func (sig Sigmoid) String() string {
+	return fmt.Sprintf("sigmoid(%s)", sig.Op)
+}
@@ -0,0 +1,34 @@
+package op
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSigmoidSimplify(t *testing.T) {
+	var testCases = []struct {
+		in  Sigmoid
+		out Operator
+	}{
+		{
+			in:  Sigmoid{Mul{Add{Const{1}, Const{2}}, Var{0}}},
+			out: Sigmoid{Mul{Const{3}, Var{0}}},
+		},
+		{
+			in:  Sigmoid{Const{0}},
+			out: Const{0.5},
+		},
+		{
+			in:  Sigmoid{Var{0}},
+			out: Sigmoid{Var{0}},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			out := tc.in.Simplify()
+			if out != tc.out {
+				t.Errorf("Expected %s, got %s", tc.out, out)
+			}
+		})
+	}
+}
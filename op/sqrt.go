@@ -0,0 +1,84 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+// safeSqrt computes the square root of the absolute value of a number.
+func safeSqrt(a float64) float64 {
+	return math.Sqrt(math.Abs(a))
+}
+
+// The Sqrt operator.
+type Sqrt struct {
+	Op Operator
+}
+
+// Eval computes the protected square root of each value.
+func (sqrt Sqrt) Eval(X [][]float64) []float64 {
+	x := sqrt.Op.Eval(X)
+	for i, xi := range x {
+		x[i] = safeSqrt(xi)
+	}
+	return x
+}
+
+// Arity of Sqrt is 1.
+func (sqrt Sqrt) Arity() uint {
+	return 1
+}
+
+// Operand returns Sqrt's operand or nil.
+func (sqrt Sqrt) Operand(i uint) Operator {
+	if i == 0 {
+		return sqrt.Op
+	}
+	return nil
+}
+
+// SetOperand replaces Sqrt's operand if i is equal to 0.
+func (sqrt Sqrt) SetOperand(i uint, op Operator) Operator {
+	if i == 0 {
+		sqrt.Op = op
+	}
+	return sqrt
+}
+
+// Simplify Sqrt.
+func (sqrt Sqrt) Simplify() Operator {
+	sqrt.Op = sqrt.Op.Simplify()
+	switch op := sqrt.Op.(type) {
+	// sqrt(a) = b
+	case Const:
+		return Const{safeSqrt(op.Value)}
+	// sqrt(x²) = |x|
+	case Square:
+		return Abs{op.Op}.Simplify()
+	// sqrt(|x|) = sqrt(x)
+	case Abs:
+		return Sqrt{op.Op}.Simplify()
+	// sqrt(-x) = sqrt(x)
+	case Neg:
+		return Sqrt{op.Op}.Simplify()
+	}
+	return sqrt
+}
+
+// Diff computes the following derivative: sqrt(|u|)' = uu' / (2|u|sqrt(|u|))
+func (sqrt Sqrt) Diff(i uint) Operator {
+	return Div{
+		Mul{sqrt.Op, sqrt.Op.Diff(i)},
+		Mul{Const{2}, Mul{Abs{sqrt.Op}, sqrt}},
+	}
+}
+
+// Name of Sqrt is "sqrt".
+func (sqrt Sqrt) Name() string {
+	return "sqrt"
+}
+
+// String formatting.
+func (sqrt Sqrt) String() string {
+	return fmt.Sprintf("sqrt(%s)", sqrt.Op)
+}
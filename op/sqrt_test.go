@@ -0,0 +1,42 @@
+package op
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSqrtSimplify(t *testing.T) {
+	var testCases = []struct {
+		in  Sqrt
+		out Operator
+	}{
+		{
+			in:  Sqrt{Mul{Add{Const{1}, Const{2}}, Var{0}}},
+			out: Sqrt{Mul{Const{3}, Var{0}}},
+		},
+		{
+			in:  Sqrt{Const{-4}},
+			out: Const{2},
+		},
+		{
+			in:  Sqrt{Square{Var{0}}},
+			out: Abs{Var{0}},
+		},
+		{
+			in:  Sqrt{Neg{Var{0}}},
+			out: Sqrt{Var{0}},
+		},
+		{
+			in:  Sqrt{Var{0}},
+			out: Sqrt{Var{0}},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			out := tc.in.Simplify()
+			if out != tc.out {
+				t.Errorf("Expected %s, got %s", tc.out, out)
+			}
+		})
+	}
+}
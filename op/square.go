@@ -64,8 +64,5 @@ func (square Square) Name() string {
 
 // String formatting.
 func (square Square) String() string {
-	if square.Op.Arity() < 2 {
-		return fmt.Sprintf("%s²", square.Op)
-	}
-	return fmt.Sprintf("%s²", parenthesize(square.Op))
+	return fmt.Sprintf("%s²", parenthesizeBase(square.Op))
 }
@@ -94,5 +94,10 @@ func (sub Sub) Name() string {
 
 // String formatting.
 func (sub Sub) String() string {
+	switch sub.Right.(type) {
+	// The right operand is parenthesized so that x-(y+z) isn't formatted as x-y+z
+	case Add, Sub:
+		return fmt.Sprintf("%s-(%s)", sub.Left, sub.Right)
+	}
 	return fmt.Sprintf("%s-%s", sub.Left, sub.Right)
 }
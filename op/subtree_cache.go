@@ -0,0 +1,141 @@
+package op
+
+import (
+	"container/list"
+	"sync"
+)
+
+// A SubtreeCache evaluates Operators on a fixed dataset and memoizes the
+// outputs of their subtrees by hash, so that the subtrees which are shared by
+// different Operators are only evaluated once. The least recently used
+// outputs are evicted once they take up more than MaxBytes. Leaves are never
+// memoized because they are cheap to evaluate. A SubtreeCache is safe to use
+// from multiple goroutines.
+type SubtreeCache struct {
+	X        [][]float64
+	MaxBytes int
+
+	mu      sync.Mutex
+	nBytes  int
+	entries map[uint64]*list.Element
+	order   *list.List
+}
+
+// NewSubtreeCache returns a SubtreeCache which evaluates Operators on X and
+// whose memoized outputs take up at most maxBytes.
+func NewSubtreeCache(X [][]float64, maxBytes int) *SubtreeCache {
+	return &SubtreeCache{
+		X:        X,
+		MaxBytes: maxBytes,
+		entries:  make(map[uint64]*list.Element),
+		order:    list.New(),
+	}
+}
+
+type subtreeEntry struct {
+	hash uint64
+	op   Operator
+	y    []float64
+}
+
+// A hashedOp is an Operator along with the hashes of each of it's subtrees.
+type hashedOp struct {
+	op       Operator
+	hash     uint64
+	operands []hashedOp
+}
+
+func newHashedOp(op Operator) hashedOp {
+	var (
+		hop    = hashedOp{op: op, operands: make([]hashedOp, op.Arity())}
+		hashes = make([]uint64, op.Arity())
+	)
+	for i := range hop.operands {
+		hop.operands[i] = newHashedOp(op.Operand(uint(i)))
+		hashes[i] = hop.operands[i].hash
+	}
+	hop.hash = hashNode(op, hashes)
+	return hop
+}
+
+// Eval returns the same output as op.Eval(sc.X). The output can safely be
+// modified.
+func (sc *SubtreeCache) Eval(op Operator) []float64 {
+	if op.Arity() == 0 {
+		return op.Eval(sc.X)
+	}
+	var (
+		y      = sc.eval(newHashedOp(op))
+		output = make([]float64, len(y))
+	)
+	copy(output, y)
+	return output
+}
+
+// eval returns the output of an Operator which isn't a leaf. The output
+// might be memoized and hence must not be modified.
+func (sc *SubtreeCache) eval(hop hashedOp) []float64 {
+	if y, ok := sc.get(hop.hash, hop.op); ok {
+		return y
+	}
+	// The operands which aren't leaves are replaced with Vars which point to
+	// their outputs, the latter being appended to the features
+	var (
+		node = hop.op
+		X    = sc.X[:len(sc.X):len(sc.X)]
+	)
+	for i, operand := range hop.operands {
+		if operand.op.Arity() == 0 {
+			continue
+		}
+		node = node.SetOperand(uint(i), Var{uint(len(X))})
+		X = append(X, sc.eval(operand))
+	}
+	var y = node.Eval(X)
+	sc.add(subtreeEntry{hash: hop.hash, op: hop.op, y: y})
+	return y
+}
+
+// get returns the memoized output of an Operator. The Operators are compared
+// with Equal in case of a hash collision.
+func (sc *SubtreeCache) get(hash uint64, op Operator) ([]float64, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if el, ok := sc.entries[hash]; ok {
+		if entry := el.Value.(subtreeEntry); Equal(entry.op, op) {
+			sc.order.MoveToFront(el)
+			return entry.y, true
+		}
+	}
+	return nil, false
+}
+
+// add memoizes an output and evicts the least recently used outputs until
+// the memory cap is respected.
+func (sc *SubtreeCache) add(entry subtreeEntry) {
+	var size = 8 * len(entry.y)
+	if size > sc.MaxBytes {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if el, ok := sc.entries[entry.hash]; ok {
+		sc.nBytes -= 8 * len(el.Value.(subtreeEntry).y)
+		sc.order.Remove(el)
+	}
+	for sc.nBytes+size > sc.MaxBytes {
+		var oldest = sc.order.Back()
+		sc.order.Remove(oldest)
+		delete(sc.entries, oldest.Value.(subtreeEntry).hash)
+		sc.nBytes -= 8 * len(oldest.Value.(subtreeEntry).y)
+	}
+	sc.entries[entry.hash] = sc.order.PushFront(entry)
+	sc.nBytes += size
+}
+
+// NBytes returns the memory taken up by the memoized outputs.
+func (sc *SubtreeCache) NBytes() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.nBytes
+}
@@ -0,0 +1,69 @@
+package op
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestSubtreeCache(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, -2, 3, 0},
+			[]float64{0.5, 4, -1, 2},
+		}
+		shared = Mul{Add{Var{0}, Const{1}}, Cos{Var{1}}}
+		ops    = []Operator{
+			Var{1},
+			Const{3},
+			shared,
+			Sub{shared, Var{0}},
+			Div{Exp{shared}, Log{Abs{shared}}},
+			If{Var{0}, shared, Neg{shared}},
+			Max{Sqrt{Var{0}}, Pow{shared, Const{2}}},
+			Add{Inv{Var{1}}, Sigmoid{Tanh{Sin{Var{0}}}}},
+			Min{Square{shared}, Var{1}},
+			scale{[]float64{2}, shared},
+		}
+		sc = NewSubtreeCache(X, 1<<20)
+	)
+	// Each Operator is evaluated twice so that the memoized outputs are used
+	for _, operators := range [][]Operator{ops, ops} {
+		for i, op := range operators {
+			t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+				var (
+					expected = op.Eval(X)
+					y        = sc.Eval(op)
+				)
+				for j := range y {
+					if math.Float64bits(y[j]) != math.Float64bits(expected[j]) {
+						t.Errorf("Expected %v, got %v", expected[j], y[j])
+					}
+				}
+				// Modifying the output mustn't modify the memoized outputs
+				for j := range y {
+					y[j] = 42
+				}
+			})
+		}
+	}
+	if sc.NBytes() == 0 {
+		t.Error("Expected some outputs to be memoized")
+	}
+}
+
+func TestSubtreeCacheMaxBytes(t *testing.T) {
+	var (
+		X  = [][]float64{[]float64{1, 2, 3, 4}}
+		sc = NewSubtreeCache(X, 2*8*len(X[0]))
+	)
+	for i := 0; i < 10; i++ {
+		sc.Eval(Add{Var{0}, Const{float64(i)}})
+		if sc.NBytes() > sc.MaxBytes {
+			t.Errorf("Expected at most %d bytes, got %d", sc.MaxBytes, sc.NBytes())
+		}
+	}
+	if sc.NBytes() != sc.MaxBytes {
+		t.Errorf("Expected %d bytes, got %d", sc.MaxBytes, sc.NBytes())
+	}
+}
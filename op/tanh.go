@@ -0,0 +1,66 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+// The Tanh operator.
+type Tanh struct {
+	Op Operator
+}
+
+// Eval computes the hyperbolic tangent of each value.
+func (tanh Tanh) Eval(X [][]float64) []float64 {
+	x := tanh.Op.Eval(X)
+	for i, xi := range x {
+		x[i] = math.Tanh(xi)
+	}
+	return x
+}
+
+// Arity of Tanh is 1.
+func (tanh Tanh) Arity() uint {
+	return 1
+}
+
+// Operand returns Tanh's operand or nil.
+func (tanh Tanh) Operand(i uint) Operator {
+	if i == 0 {
+		return tanh.Op
+	}
+	return nil
+}
+
+// SetOperand replaces Tanh's operand if i is equal to 0.
+func (tanh Tanh) SetOperand(i uint, op Operator) Operator {
+	if i == 0 {
+		tanh.Op = op
+	}
+	return tanh
+}
+
+// Simplify Tanh.
+func (tanh Tanh) Simplify() Operator {
+	tanh.Op = tanh.Op.Simplify()
+	switch op := tanh.Op.(type) {
+	case Const:
+		return Const{math.Tanh(op.Value)}
+	}
+	return tanh
+}
+
+// Diff computes the following derivative: tanh(u)' = u' * (1 - tanh(u)²)
+func (tanh Tanh) Diff(i uint) Operator {
+	return Mul{tanh.Op.Diff(i), Sub{Const{1}, Square{tanh}}}
+}
+
+// Name of Tanh is "tanh".
+func (tanh Tanh) Name() string {
+	return "tanh"
+}
+
+// String formatting.
+func (tanh Tanh) String() string {
+	return fmt.Sprintf("tanh(%s)", tanh.Op)
+}
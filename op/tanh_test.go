@@ -0,0 +1,34 @@
+package op
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTanhSimplify(t *testing.T) {
+	var testCases = []struct {
+		in  Tanh
+		out Operator
+	}{
+		{
+			in:  Tanh{Mul{Add{Const{1}, Const{2}}, Var{0}}},
+			out: Tanh{Mul{Const{3}, Var{0}}},
+		},
+		{
+			in:  Tanh{Const{0}},
+			out: Const{0},
+		},
+		{
+			in:  Tanh{Var{0}},
+			out: Tanh{Var{0}},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			out := tc.in.Simplify()
+			if out != tc.out {
+				t.Errorf("Expected %s, got %s", tc.out, out)
+			}
+		})
+	}
+}
@@ -1,15 +1,277 @@
 package xgp
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
 
+	"github.com/MaxHalford/xgp/metrics"
 	"github.com/MaxHalford/xgp/op"
 	xrand "golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/optimize"
 )
 
+// checkPolishMethod returns an error if a polishing method doesn't exist or
+// if it can't be used with a loss metric. CMA-ES works with any metric, LM
+// requires a least squares metric and BFGS requires a differentiable metric.
+func checkPolishMethod(method string, loss metrics.Metric) error {
+	switch method {
+	case "", "cmaes":
+		return nil
+	case "lm":
+		switch loss.(type) {
+		case metrics.MSE, metrics.RMSE:
+			return nil
+		}
+		return fmt.Errorf("The '%s' metric can't be used with LM polishing because it isn't a least squares metric", loss.String())
+	case "bfgs":
+		if _, ok := loss.(metrics.DiffMetric); ok {
+			return nil
+		}
+		return fmt.Errorf("The '%s' metric can't be used with BFGS polishing because it is not differentiable", loss.String())
+	}
+	return fmt.Errorf("Unknown polishing method '%s', has to be one of ('cmaes', 'lm', 'bfgs')", method)
+}
+
+// settings returns the optimize.Settings which limit the number of
+// iterations, maxIter being 0 meaning no limit.
+func settings(maxIter int) *optimize.Settings {
+	if maxIter == 0 {
+		return nil
+	}
+	return &optimize.Settings{MajorIterations: maxIter}
+}
+
+// minimizeConsts looks for the values of a set of constants that minimize a
+// given function with CMA-ES.
+func minimizeConsts(consts []float64, f func(x []float64) float64, maxIter int, rng *rand.Rand) ([]float64, error) {
+	var (
+		problem = optimize.Problem{Func: f}
+		method  = &optimize.CmaEsChol{
+			Population: int(15 + math.Floor(3*math.Log(float64(len(consts))))), // MAGIC
+			Src:        xrand.NewSource(rng.Uint64()),
+		}
+	)
+
+	// Run the optimisation
+	result, err := optimize.Minimize(problem, consts, settings(maxIter), method)
+	if err != nil {
+		return consts, err
+	}
+	return result.X, nil
+}
+
+// constDerivatives contains the derivatives of an Operator with respect to
+// it's Consts. The Consts are replaced with Vars whose values are appended to
+// the features.
+type constDerivatives struct {
+	param op.Operator
+	diffs []op.Operator
+}
+
+func newConstDerivatives(operator op.Operator, nFeatures int) constDerivatives {
+	param, diffs := op.DiffConsts(operator, uint(nFeatures))
+	return constDerivatives{param: param, diffs: diffs}
+}
+
+// features appends a column for each Const to X.
+func (cd constDerivatives) features(X [][]float64, consts []float64) [][]float64 {
+	var XC = make([][]float64, len(X), len(X)+len(consts))
+	copy(XC, X)
+	for _, c := range consts {
+		var col = make([]float64, len(X[0]))
+		for i := range col {
+			col[i] = c
+		}
+		XC = append(XC, col)
+	}
+	return XC
+}
+
+// jacobian returns the derivatives of the Operator with respect to each
+// Const. The output contains one slice per Const.
+func (cd constDerivatives) jacobian(XC [][]float64) [][]float64 {
+	var jac = make([][]float64, len(cd.diffs))
+	for i, diff := range cd.diffs {
+		jac[i] = evalOp(diff, XC)
+	}
+	return jac
+}
+
+// weight returns the ith weight, weights being uniform if W is nil.
+func weight(W []float64, i int) float64 {
+	if W == nil {
+		return 1
+	}
+	return W[i]
+}
+
+func isFinite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+// levenbergMarquardt minimizes the weighted sum of squared errors between the
+// output of an Operator and Y by modifying the Operator's Consts. The damping
+// parameter is decreased after each successful step and increased otherwise.
+func levenbergMarquardt(cd constDerivatives, X [][]float64, Y, W, consts []float64, maxIter int) []float64 {
+	if maxIter == 0 {
+		maxIter = 100 // MAGIC
+	}
+	const (
+		maxLambda = 1e10  // MAGIC
+		tol       = 1e-10 // MAGIC
+	)
+	var (
+		p      = len(consts)
+		x      = append([]float64{}, consts...)
+		lambda = 1e-3 // MAGIC
+		sse    = func(yPred []float64) float64 {
+			var sum float64
+			for i, y := range Y {
+				sum += weight(W, i) * (yPred[i] - y) * (yPred[i] - y)
+			}
+			return sum
+		}
+		XC    = cd.features(X, x)
+		yPred = evalOp(cd.param, XC)
+		cost  = sse(yPred)
+	)
+	if !isFinite(cost) {
+		return consts
+	}
+	for iter := 0; iter < maxIter; iter++ {
+		// Build the normal equations
+		var (
+			jac = cd.jacobian(XC)
+			jtj = mat.NewSymDense(p, nil)
+			jtr = mat.NewVecDense(p, nil)
+		)
+		for a := 0; a < p; a++ {
+			for b := a; b < p; b++ {
+				var s float64
+				for i := range Y {
+					s += weight(W, i) * jac[a][i] * jac[b][i]
+				}
+				jtj.SetSym(a, b, s)
+			}
+			var s float64
+			for i, y := range Y {
+				s += weight(W, i) * jac[a][i] * (yPred[i] - y)
+			}
+			jtr.SetVec(a, s)
+		}
+		if !isFinite(mat.Sum(jtj)) || !isFinite(mat.Sum(jtr)) {
+			break
+		}
+		// Increase the damping until a step decreases the cost
+		var improved bool
+		for ; lambda < maxLambda; lambda *= 10 {
+			var A = mat.NewSymDense(p, nil)
+			A.CopySym(jtj)
+			for a := 0; a < p; a++ {
+				A.SetSym(a, a, jtj.At(a, a)+lambda*math.Max(jtj.At(a, a), 1e-12))
+			}
+			var (
+				chol mat.Cholesky
+				step mat.VecDense
+			)
+			if !chol.Factorize(A) || chol.SolveVecTo(&step, jtr) != nil {
+				continue
+			}
+			var candidate = make([]float64, p)
+			for a := range candidate {
+				candidate[a] = x[a] - step.AtVec(a)
+			}
+			var (
+				XCand = cd.features(X, candidate)
+				yCand = evalOp(cd.param, XCand)
+				c     = sse(yCand)
+			)
+			if isFinite(c) && c < cost {
+				improved = cost-c > tol*cost
+				x, XC, yPred, cost = candidate, XCand, yCand, c
+				lambda = math.Max(lambda/10, 1e-12)
+				break
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return x
+}
+
+// minimizeConstsBFGS minimizes a differentiable loss metric with respect to
+// the Consts of a Program with the BFGS method. The gradient of the loss
+// with respect to the Program's output is obtained from the metric and is
+// then multiplied by the derivatives of the output with respect to the
+// Consts.
+func minimizeConstsBFGS(prog Program, consts []float64, maxIter int) ([]float64, error) {
+	var (
+		gp   = prog.GP
+		loss = gp.LossMetric.(metrics.DiffMetric)
+		cd   = newConstDerivatives(prog.Op, len(gp.X))
+		ws   float64
+	)
+	for i := range gp.Y {
+		ws += weight(gp.W, i)
+	}
+	var predict = func(XC [][]float64) ([]float64, error) {
+		return Program{GP: gp, Op: cd.param}.Predict(XC, loss.NeedsProbabilities())
+	}
+	var problem = optimize.Problem{
+		Func: func(x []float64) float64 {
+			yPred, err := predict(cd.features(gp.X, x))
+			if err != nil {
+				return math.Inf(1)
+			}
+			fitness, err := loss.Apply(gp.Y, yPred, gp.W)
+			if err != nil || !isFinite(fitness) {
+				return math.Inf(1)
+			}
+			return fitness
+		},
+		Grad: func(grad, x []float64) {
+			for j := range grad {
+				grad[j] = 0
+			}
+			var XC = cd.features(gp.X, x)
+			yPred, err := predict(XC)
+			if err != nil {
+				return
+			}
+			g, err := loss.Gradients(gp.Y, yPred)
+			if err != nil {
+				return
+			}
+			for j, d := range cd.jacobian(XC) {
+				for i, gi := range g {
+					grad[j] += weight(gp.W, i) * gi * d[i] / ws
+				}
+				if !isFinite(grad[j]) {
+					grad[j] = 0
+				}
+			}
+		},
+	}
+	result, err := optimize.Minimize(problem, consts, settings(maxIter), &optimize.BFGS{})
+	if result == nil {
+		return consts, err
+	}
+	// The line search might fail once no progress can be made, in which case
+	// the best values found so far are still valid
+	return result.X, nil
+}
+
+// polishProgram optimizes the Consts of a Program until convergence.
 func polishProgram(prog Program, rng *rand.Rand) (Program, error) {
+	return optimizeProgram(prog, 0, rng)
+}
+
+// optimizeProgram optimizes the Consts of a Program with the GP's
+// PolishMethod for at most maxIter iterations, 0 meaning no limit.
+func optimizeProgram(prog Program, maxIter int, rng *rand.Rand) (Program, error) {
 	// Extract the Program's Consts
 	var consts = op.GetConsts(prog.Op)
 
@@ -18,28 +280,104 @@ func polishProgram(prog Program, rng *rand.Rand) (Program, error) {
 		return prog, nil
 	}
 
-	var (
-		problem = optimize.Problem{
-			Func: func(x []float64) float64 {
-				fitness, _ := Program{
-					Op: op.SetConsts(prog.Op, x),
-					GP: prog.GP,
-				}.Evaluate()
-				return fitness
-			},
-		}
-		method = &optimize.CmaEsChol{
-			Population: int(15 + math.Floor(3*math.Log(float64(len(consts))))), // MAGIC
-			Src:        xrand.NewSource(rng.Uint64()),
+	// Use the derivatives with respect to the Consts if possible, in which
+	// case the Scaling is optimized along with the Consts
+	switch prog.GP.PolishMethod {
+	case "lm", "bfgs":
+		var scaled = Program{GP: prog.GP, Op: prog.ScaledOp()}
+		consts = op.GetConsts(scaled.Op)
+		if prog.GP.PolishMethod == "lm" {
+			var cd = newConstDerivatives(scaled.Op, len(prog.GP.X))
+			consts = levenbergMarquardt(cd, prog.GP.X, prog.GP.Y, prog.GP.W, consts, maxIter)
+		} else {
+			var err error
+			if consts, err = minimizeConstsBFGS(scaled, consts, maxIter); err != nil {
+				return prog, err
+			}
 		}
-	)
+		if prog.Scaling != nil {
+			prog.Scaling = &Scaling{Intercept: consts[0], Slope: consts[1]}
+			consts = consts[2:]
+		}
+		prog.Op = op.SetConsts(prog.Op, consts)
+		return prog, nil
+	}
 
-	// Run the optimisation
-	result, err := optimize.Minimize(problem, consts, nil, method)
+	polished, err := minimizeConsts(
+		consts,
+		func(x []float64) float64 {
+			fitness, _ := (&Program{
+				Op: op.SetConsts(prog.Op, x),
+				GP: prog.GP,
+			}).Evaluate()
+			return fitness
+		},
+		maxIter,
+		rng,
+	)
 	if err != nil {
 		return prog, err
 	}
 
-	prog.Op = op.SetConsts(prog.Op, result.X)
+	prog.Op = op.SetConsts(prog.Op, polished)
 	return prog, nil
 }
+
+// polishMultiProgram optimizes the Consts of a MultiProgram until
+// convergence.
+func polishMultiProgram(mp MultiProgram, rng *rand.Rand) (MultiProgram, error) {
+	return optimizeMultiProgram(mp, 0, rng)
+}
+
+// optimizeMultiProgram optimizes the Consts of a MultiProgram with CMA-ES for
+// at most maxIter iterations, 0 meaning no limit.
+func optimizeMultiProgram(mp MultiProgram, maxIter int, rng *rand.Rand) (MultiProgram, error) {
+	// Extract the Consts of each Operator and concatenate them
+	var (
+		consts = make([]float64, 0)
+		sizes  = make([]int, len(mp.Ops))
+	)
+	for i, operator := range mp.Ops {
+		var c = op.GetConsts(operator)
+		sizes[i] = len(c)
+		consts = append(consts, c...)
+	}
+
+	// If there are no Consts then nothing can be done
+	if len(consts) == 0 {
+		return mp, nil
+	}
+
+	// setConsts dispatches the concatenated Consts to each Operator
+	var setConsts = func(x []float64) []op.Operator {
+		var (
+			ops    = make([]op.Operator, len(mp.Ops))
+			offset int
+		)
+		for i, operator := range mp.Ops {
+			ops[i] = op.SetConsts(operator, x[offset:offset+sizes[i]])
+			offset += sizes[i]
+		}
+		return ops
+	}
+
+	polished, err := minimizeConsts(
+		consts,
+		func(x []float64) float64 {
+			fitness, _ := (&MultiProgram{
+				GP:      mp.GP,
+				Ops:     setConsts(x),
+				Classes: mp.Classes,
+			}).Evaluate()
+			return fitness
+		},
+		maxIter,
+		rng,
+	)
+	if err != nil {
+		return mp, err
+	}
+
+	mp.Ops = setConsts(polished)
+	return mp, nil
+}
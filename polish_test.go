@@ -60,3 +60,89 @@ func TestPolish(t *testing.T) {
 		})
 	}
 }
+
+func TestPolishMethods(t *testing.T) {
+	var (
+		X = [][]float64{[]float64{0, 0.5, 1, 1.5, 2, 2.5, 3, 3.5, 4}}
+		Y = make([]float64, len(X[0]))
+	)
+	// y = 3 * exp(-0.5 * x) + 1
+	for i, x := range X[0] {
+		Y[i] = 3*math.Exp(-0.5*x) + 1
+	}
+	for _, method := range []string{"cmaes", "lm", "bfgs"} {
+		t.Run(method, func(t *testing.T) {
+			var prog = Program{
+				Op: op.Add{op.Mul{op.Const{1}, op.Exp{op.Mul{op.Const{-1}, op.Var{0}}}}, op.Const{0}},
+				GP: &GP{
+					GPConfig:   GPConfig{PolishMethod: method},
+					X:          X,
+					Y:          Y,
+					LossMetric: metrics.MSE{},
+				},
+			}
+			polished, err := polishProgram(prog, rand.New(rand.NewSource(42)))
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			var (
+				consts   = op.GetConsts(polished.Op)
+				expected = []float64{3, -0.5, 1}
+			)
+			for i, c := range consts {
+				if math.Abs(c-expected[i]) > 0.001 {
+					t.Errorf("Expected %v, got %v", expected, consts)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestPolishBFGSClassification(t *testing.T) {
+	var prog = Program{
+		Op: op.Mul{op.Const{0.1}, op.Sub{op.Var{0}, op.Const{0}}},
+		GP: &GP{
+			GPConfig:   GPConfig{PolishMethod: "bfgs"},
+			X:          [][]float64{[]float64{0, 1, 2, 3, 4, 5, 6, 7}},
+			Y:          []float64{0, 0, 0, 1, 0, 1, 1, 1},
+			LossMetric: metrics.LogLoss{},
+		},
+	}
+	before, _ := prog.Evaluate()
+	polished, err := polishProgram(prog, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	after, _ := polished.Evaluate()
+	if after >= before {
+		t.Errorf("Expected the logloss to decrease from %.5f, got %.5f", before, after)
+	}
+}
+
+func TestCheckPolishMethod(t *testing.T) {
+	var testCases = []struct {
+		method      string
+		loss        metrics.Metric
+		raisesError bool
+	}{
+		{method: "", loss: metrics.F1{}, raisesError: false},
+		{method: "cmaes", loss: metrics.F1{}, raisesError: false},
+		{method: "lm", loss: metrics.MSE{}, raisesError: false},
+		{method: "lm", loss: metrics.RMSE{}, raisesError: false},
+		{method: "lm", loss: metrics.LogLoss{}, raisesError: true},
+		{method: "bfgs", loss: metrics.LogLoss{}, raisesError: false},
+		{method: "bfgs", loss: metrics.Accuracy{}, raisesError: true},
+		{method: "newton", loss: metrics.MSE{}, raisesError: true},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var err = checkPolishMethod(tc.method, tc.loss)
+			if (err != nil) != tc.raisesError {
+				t.Errorf("Expected error: %t, got %v", tc.raisesError, err)
+			}
+		})
+	}
+}
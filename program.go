@@ -13,11 +13,78 @@ import (
 type Program struct {
 	*GP
 	Op op.Operator
+	// Scaling is applied to the outputs of Op, it is nil unless linear
+	// scaling is used
+	Scaling *Scaling
+	// residuals are the errors made on each training sample, they are only
+	// computed if the selection method needs them
+	residuals []float64
+}
+
+// A Scaling is an affine transformation applied to the outputs of a Program.
+type Scaling struct {
+	Intercept float64 `json:"intercept"`
+	Slope     float64 `json:"slope"`
+}
+
+// fitScaling returns the Scaling which minimizes the weighted squared error
+// between the scaled outputs and Y. The Slope is 0 if the outputs are
+// constant, in which case the Intercept is the weighted mean of Y.
+func fitScaling(yRaw, Y, W []float64) *Scaling {
+	var yMean, rawMean, wSum float64
+	for i, y := range Y {
+		var w = weight(W, i)
+		yMean += w * y
+		rawMean += w * yRaw[i]
+		wSum += w
+	}
+	if wSum == 0 {
+		return &Scaling{Slope: 1}
+	}
+	yMean /= wSum
+	rawMean /= wSum
+	var cov, variance float64
+	for i, y := range Y {
+		var (
+			w = weight(W, i)
+			d = yRaw[i] - rawMean
+		)
+		cov += w * d * (y - yMean)
+		variance += w * d * d
+	}
+	var scaling = &Scaling{Intercept: yMean}
+	if variance > 0 && isFinite(cov/variance) {
+		scaling.Slope = cov / variance
+		scaling.Intercept -= scaling.Slope * rawMean
+	}
+	return scaling
+}
+
+// scale applies the Program's Scaling to raw outputs in place.
+func (prog Program) scale(yRaw []float64) {
+	if prog.Scaling == nil {
+		return
+	}
+	for i, y := range yRaw {
+		yRaw[i] = prog.Scaling.Intercept + prog.Scaling.Slope*y
+	}
+}
+
+// ScaledOp returns the Operator of the Program with the Scaling applied to
+// it, which is simply Op if there is no Scaling.
+func (prog Program) ScaledOp() op.Operator {
+	if prog.Scaling == nil {
+		return prog.Op
+	}
+	return op.Add{
+		op.Const{Value: prog.Scaling.Intercept},
+		op.Mul{op.Const{Value: prog.Scaling.Slope}, prog.Op},
+	}
 }
 
 // String formatting.
 func (prog Program) String() string {
-	return prog.Op.String()
+	return prog.ScaledOp().String()
 }
 
 // Classification determines if the Program has to perform classification or
@@ -31,10 +98,25 @@ func (prog Program) classification() bool {
 	return false
 }
 
+// evalOp evaluates an Operator once it has been compiled to a Bytecode, which
+// is faster than calling it's Eval method and produces the same outputs.
+func evalOp(operator op.Operator, X [][]float64) []float64 {
+	bc, err := op.Compile(operator)
+	if err != nil {
+		return operator.Eval(X)
+	}
+	return bc.Eval(X)
+}
+
 // Predict predicts the output of a slice of features.
 func (prog Program) Predict(X [][]float64, proba bool) ([]float64, error) {
-	// Make predictions
-	yPred := prog.Op.Eval(X)
+	return prog.transform(evalOp(prog.Op, X), proba)
+}
+
+// transform turns the raw outputs of the Program's Operator into predictions.
+func (prog Program) transform(yPred []float64, proba bool) ([]float64, error) {
+	// Apply the linear scaling
+	prog.scale(yPred)
 	// Check the predictions don't contain any NaNs
 	if floats.HasNaN(yPred) {
 		return nil, errors.New("yPred contains NaNs")
@@ -67,6 +149,7 @@ func (prog Program) PredictPartial(x []float64, proba bool) (float64, error) {
 
 type serialProgram struct {
 	Op         op.SerialOp `json:"op"`
+	Scaling    *Scaling    `json:"scaling,omitempty"`
 	LossMetric string      `json:"loss_metric"`
 }
 
@@ -74,6 +157,7 @@ type serialProgram struct {
 func (prog Program) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&serialProgram{
 		Op:         op.SerializeOp(prog.Op),
+		Scaling:    prog.Scaling,
 		LossMetric: prog.GP.LossMetric.String(),
 	})
 }
@@ -93,6 +177,7 @@ func (prog *Program) UnmarshalJSON(bytes []byte) error {
 		return err
 	}
 	prog.Op = operator
+	prog.Scaling = serial.Scaling
 	prog.GP = &GP{LossMetric: loss}
 	return nil
 }
@@ -22,7 +22,7 @@ func TestProgramPredict(t *testing.T) {
 				[]float64{0.1, -0.3, 0.4, 1},
 				[]float64{-0.3, 0.4, 0.2, 2},
 			},
-			program:     Program{nil, op.Add{op.Var{0}, op.Var{1}}},
+			program:     Program{Op: op.Add{op.Var{0}, op.Var{1}}},
 			proba:       false,
 			y:           []float64{-0.2, 0.1, 0.6, 3},
 			raisesError: false,
@@ -131,6 +131,20 @@ func TestProgramPredict(t *testing.T) {
 			y:           nil,
 			raisesError: true,
 		},
+		{
+			X: [][]float64{
+				[]float64{0.1, -0.3, 0.4, 1},
+				[]float64{-0.3, 0.4, 0.2, 2},
+			},
+			program: Program{
+				Op:      op.Add{op.Var{0}, op.Var{1}},
+				Scaling: &Scaling{Intercept: 1, Slope: 2},
+				GP:      &GP{LossMetric: metrics.MSE{}},
+			},
+			proba:       false,
+			y:           []float64{0.6, 1.2, 2.2, 7},
+			raisesError: false,
+		},
 	}
 	for i, tc := range testCases {
 		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
@@ -170,3 +184,63 @@ func TestProgramMarshalJSON(t *testing.T) {
 		return
 	}
 }
+
+func TestProgramMarshalJSONScaling(t *testing.T) {
+	var (
+		prog = Program{
+			Op:      op.Add{op.Var{0}, op.Const{42}},
+			Scaling: &Scaling{Intercept: -1, Slope: 3},
+			GP:      &GP{LossMetric: metrics.MSE{}},
+		}
+		bytes, err = prog.MarshalJSON()
+	)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	var newProg = Program{}
+	if err = newProg.UnmarshalJSON(bytes); err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	if newProg.Scaling == nil || *newProg.Scaling != *prog.Scaling {
+		t.Errorf("Expected %v, got %v", prog.Scaling, newProg.Scaling)
+	}
+}
+
+func TestFitScaling(t *testing.T) {
+	var testCases = []struct {
+		yRaw     []float64
+		Y        []float64
+		W        []float64
+		expected Scaling
+	}{
+		{
+			yRaw:     []float64{1, 2, 3},
+			Y:        []float64{5, 7, 9},
+			W:        nil,
+			expected: Scaling{Intercept: 3, Slope: 2},
+		},
+		{
+			yRaw:     []float64{2, 2, 2},
+			Y:        []float64{1, 2, 6},
+			W:        nil,
+			expected: Scaling{Intercept: 3, Slope: 0},
+		},
+		{
+			yRaw:     []float64{0, 1, 2},
+			Y:        []float64{1, 2, 100},
+			W:        []float64{1, 1, 0},
+			expected: Scaling{Intercept: 1, Slope: 1},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var scaling = fitScaling(tc.yRaw, tc.Y, tc.W)
+			if math.Abs(scaling.Intercept-tc.expected.Intercept) > 10e-10 ||
+				math.Abs(scaling.Slope-tc.expected.Slope) > 10e-10 {
+				t.Errorf("Expected %v, got %v", tc.expected, *scaling)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package xgp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/MaxHalford/eaopt"
+
+	"github.com/MaxHalford/xgp/metrics"
+)
+
+// newSelector returns the eaopt.Selector corresponding to the GP's Selection
+// method. loss is the loss metric before being negated.
+func (gp *GP) newSelector(loss metrics.Metric) (eaopt.Selector, error) {
+	switch gp.Selection {
+	case "", "tournament":
+		return eaopt.SelTournament{NContestants: gp.TournamentSize}, nil
+	case "lexicase", "eps_lexicase":
+		rm, ok := loss.(metrics.ResidualMetric)
+		if !ok {
+			return nil, fmt.Errorf("The '%s' metric can't be used with lexicase selection because it can't compute residuals", loss.String())
+		}
+		gp.residualMetric = rm
+		return selLexicase{epsilon: gp.Selection == "eps_lexicase"}, nil
+	case "double_tournament":
+		return selDoubleTournament{
+			nContestants: gp.TournamentSize,
+			sizePressure: gp.SizePressure,
+			gp:           gp,
+		}, nil
+	}
+	return nil, fmt.Errorf("Unknown selection method '%s', has to be one of "+
+		"('tournament', 'lexicase', 'eps_lexicase', 'double_tournament')", gp.Selection)
+}
+
+// residuals returns the error made on each training sample if the selection
+// method needs them and nil otherwise. NaNs are replaced by +Inf so that they
+// are never preferred.
+func (gp GP) residuals(yPred []float64) ([]float64, error) {
+	if gp.residualMetric == nil {
+		return nil, nil
+	}
+	res, err := gp.residualMetric.Residuals(gp.Y, yPred)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range res {
+		if math.IsNaN(r) {
+			res[i] = math.Inf(1)
+		}
+	}
+	return res, nil
+}
+
+// residualsOf returns the residuals of a Program or of a MultiProgram.
+func residualsOf(genome eaopt.Genome) []float64 {
+	switch genome := genome.(type) {
+	case *Program:
+		return genome.residuals
+	case *MultiProgram:
+		return genome.residuals
+	}
+	return nil
+}
+
+// caseError returns the error made on a case, missing errors being infinite.
+func caseError(residuals []float64, c int) float64 {
+	if c < len(residuals) {
+		return residuals[c]
+	}
+	return math.Inf(1)
+}
+
+// median returns the median of a slice, which is modified in place.
+func median(x []float64) float64 {
+	if len(x) == 0 {
+		return 0
+	}
+	sort.Float64s(x)
+	var m = len(x) / 2
+	if len(x)%2 == 1 {
+		return x[m]
+	}
+	return (x[m-1] + x[m]) / 2
+}
+
+// selLexicase implements lexicase selection. Each selection goes through the
+// training samples in a random order and only keeps the candidates which make
+// the lowest error on each sample, until a single candidate remains. With
+// epsilon-lexicase the candidates which are within the median absolute
+// deviation of the errors of the whole population are also kept, which works
+// better for regression. cases can be set with the withCases method so that
+// the residuals and the epsilons of a population are computed once per
+// generation instead of once per call to Apply.
+type selLexicase struct {
+	epsilon bool
+	cases   *lexicaseCases
+}
+
+// lexicaseCases holds the residuals of the individuals of a population along
+// with the epsilons, which are memoized because they only depend on the
+// population.
+type lexicaseCases struct {
+	indis     eaopt.Individuals
+	residuals [][]float64
+	nCases    int
+	epsilons  map[int]float64
+}
+
+func newLexicaseCases(indis eaopt.Individuals) *lexicaseCases {
+	var cases = &lexicaseCases{
+		indis:     indis,
+		residuals: make([][]float64, len(indis)),
+		epsilons:  make(map[int]float64),
+	}
+	for i, indi := range indis {
+		cases.residuals[i] = residualsOf(indi.Genome)
+		if len(cases.residuals[i]) > cases.nCases {
+			cases.nCases = len(cases.residuals[i])
+		}
+	}
+	return cases
+}
+
+// withCases returns a copy of the selLexicase which reuses the residuals of
+// the given Individuals as long as it is applied to them.
+func (sel selLexicase) withCases(indis eaopt.Individuals) selLexicase {
+	sel.cases = newLexicaseCases(indis)
+	return sel
+}
+
+// Apply is necessary to implement eaopt.Selector.
+func (sel selLexicase) Apply(n uint, indis eaopt.Individuals, rng *rand.Rand) (eaopt.Individuals, []int, error) {
+	var (
+		cases    = sel.cases
+		selected = make(eaopt.Individuals, n)
+		indexes  = make([]int, n)
+	)
+	if cases == nil || !sameIndividuals(cases.indis, indis) {
+		cases = newLexicaseCases(indis)
+	}
+	for i := range selected {
+		indexes[i] = sel.selectOne(cases.residuals, cases.nCases, cases.epsilons, rng)
+		selected[i] = indis[indexes[i]].Clone(rng)
+	}
+	return selected, indexes, nil
+}
+
+// sameIndividuals indicates if two slices of Individuals share the same
+// backing array and the same length.
+func sameIndividuals(a, b eaopt.Individuals) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// epsilonOf returns the median absolute deviation of the errors made on a
+// case. The values are memoized because they only depend on the population.
+func (sel selLexicase) epsilonOf(residuals [][]float64, c int, epsilons map[int]float64) float64 {
+	if eps, ok := epsilons[c]; ok {
+		return eps
+	}
+	var errs = make([]float64, 0, len(residuals))
+	for _, res := range residuals {
+		if e := caseError(res, c); !math.IsInf(e, 0) {
+			errs = append(errs, e)
+		}
+	}
+	var m = median(errs)
+	for i, e := range errs {
+		errs[i] = math.Abs(e - m)
+	}
+	var eps = median(errs)
+	epsilons[c] = eps
+	return eps
+}
+
+func (sel selLexicase) selectOne(residuals [][]float64, nCases int, epsilons map[int]float64, rng *rand.Rand) int {
+	var candidates = make([]int, len(residuals))
+	for i := range candidates {
+		candidates[i] = i
+	}
+	for _, c := range rng.Perm(nCases) {
+		if len(candidates) == 1 {
+			break
+		}
+		var best = math.Inf(1)
+		for _, i := range candidates {
+			if e := caseError(residuals[i], c); e < best {
+				best = e
+			}
+		}
+		if sel.epsilon {
+			best += sel.epsilonOf(residuals, c, epsilons)
+		}
+		var survivors = candidates[:0]
+		for _, i := range candidates {
+			if caseError(residuals[i], c) <= best {
+				survivors = append(survivors, i)
+			}
+		}
+		candidates = survivors
+	}
+	return candidates[rng.Intn(len(candidates))]
+}
+
+// Validate is necessary to implement eaopt.Selector.
+func (sel selLexicase) Validate() error {
+	return nil
+}
+
+// selDoubleTournament implements the fitness first double tournament of Luke
+// and Panait. Two individuals are chosen with fitness tournaments, after which
+// the smallest one is selected with probability sizePressure / 2. sizePressure
+// has to be between 1 and 2, 1 meaning that size doesn't matter.
+type selDoubleTournament struct {
+	nContestants uint
+	sizePressure float64
+	gp           *GP
+}
+
+// Apply is necessary to implement eaopt.Selector.
+func (sel selDoubleTournament) Apply(n uint, indis eaopt.Individuals, rng *rand.Rand) (eaopt.Individuals, []int, error) {
+	var (
+		selected   = make(eaopt.Individuals, n)
+		indexes    = make([]int, n)
+		tournament = func() int {
+			var winner = rng.Intn(len(indis))
+			for i := uint(1); i < sel.nContestants; i++ {
+				if j := rng.Intn(len(indis)); indis[j].Fitness < indis[winner].Fitness {
+					winner = j
+				}
+			}
+			return winner
+		}
+	)
+	for i := range selected {
+		var small, large = tournament(), tournament()
+		if sel.gp.complexity(indis[large].Genome) < sel.gp.complexity(indis[small].Genome) {
+			small, large = large, small
+		}
+		if rng.Float64() < sel.sizePressure/2 {
+			indexes[i] = small
+		} else {
+			indexes[i] = large
+		}
+		selected[i] = indis[indexes[i]].Clone(rng)
+	}
+	return selected, indexes, nil
+}
+
+// Validate is necessary to implement eaopt.Selector.
+func (sel selDoubleTournament) Validate() error {
+	if sel.nContestants < 1 {
+		return errors.New("The tournament size should be higher than 0")
+	}
+	if sel.sizePressure < 1 || sel.sizePressure > 2 {
+		return errors.New("The size pressure should be between 1 and 2")
+	}
+	return nil
+}
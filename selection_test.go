@@ -0,0 +1,195 @@
+package xgp
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/MaxHalford/eaopt"
+
+	"github.com/MaxHalford/xgp/metrics"
+	"github.com/MaxHalford/xgp/op"
+)
+
+func TestSelLexicase(t *testing.T) {
+	var testCases = []struct {
+		residuals [][]float64
+		epsilon   bool
+		expected  map[int]bool
+	}{
+		// The specialist is never worse than the generalists on any case
+		{
+			residuals: [][]float64{{1, 1, 1}, {0, 1, 1}, {2, 0, 2}},
+			epsilon:   false,
+			expected:  map[int]bool{1: true, 2: true},
+		},
+		// The best on average is never selected with lexicase
+		{
+			residuals: [][]float64{{1, 1}, {0, 10}, {10, 0}},
+			epsilon:   false,
+			expected:  map[int]bool{1: true, 2: true},
+		},
+		// Missing residuals are infinite
+		{
+			residuals: [][]float64{nil, {5, 5}},
+			epsilon:   false,
+			expected:  map[int]bool{1: true},
+		},
+		// Small differences are ignored with epsilon-lexicase
+		{
+			residuals: [][]float64{{0, 0}, {0.1, 0.1}, {10, 10}},
+			epsilon:   true,
+			expected:  map[int]bool{0: true, 1: true},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var (
+				indis = make(eaopt.Individuals, len(tc.residuals))
+				rng   = rand.New(rand.NewSource(42))
+				sel   = selLexicase{epsilon: tc.epsilon}
+			)
+			for j, res := range tc.residuals {
+				indis[j] = eaopt.Individual{Genome: &Program{Op: op.Const{Value: float64(j)}, residuals: res}}
+			}
+			_, indexes, err := sel.Apply(100, indis, rng)
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			var seen = make(map[int]bool)
+			for _, idx := range indexes {
+				if !tc.expected[idx] {
+					t.Errorf("Individual %d should not have been selected", idx)
+					return
+				}
+				seen[idx] = true
+			}
+			if len(seen) != len(tc.expected) {
+				t.Errorf("Expected %d distinct individuals, got %d", len(tc.expected), len(seen))
+			}
+		})
+	}
+}
+
+func TestSelLexicaseWithCases(t *testing.T) {
+	var (
+		indis  = make(eaopt.Individuals, 3)
+		others = make(eaopt.Individuals, 2)
+	)
+	for j, res := range [][]float64{{1, 1, 1}, {0, 1, 1}, {2, 0, 2}} {
+		indis[j] = eaopt.Individual{Genome: &Program{Op: op.Const{Value: float64(j)}, residuals: res}}
+	}
+	for j, res := range [][]float64{{0, 0, 0}, {1, 1, 1}} {
+		others[j] = eaopt.Individual{Genome: &Program{Op: op.Const{Value: float64(j)}, residuals: res}}
+	}
+	for _, epsilon := range []bool{false, true} {
+		var (
+			sel    = selLexicase{epsilon: epsilon}
+			cached = sel.withCases(indis)
+		)
+		// Reusing the residuals doesn't change the selected individuals
+		_, expected, _ := sel.Apply(50, indis, rand.New(rand.NewSource(42)))
+		_, indexes, err := cached.Apply(50, indis, rand.New(rand.NewSource(42)))
+		if err != nil {
+			t.Errorf("Expected nil, got %s", err)
+			return
+		}
+		if fmt.Sprint(indexes) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, indexes)
+		}
+		// The residuals of other individuals are gathered again
+		_, indexes, _ = cached.Apply(10, others, rand.New(rand.NewSource(42)))
+		for _, idx := range indexes {
+			if idx != 0 {
+				t.Errorf("Expected 0, got %d", idx)
+			}
+		}
+	}
+}
+
+func TestSelDoubleTournament(t *testing.T) {
+	var (
+		gp    = &GP{}
+		rng   = rand.New(rand.NewSource(42))
+		indis = eaopt.Individuals{
+			{Genome: &Program{Op: op.Add{op.Var{Index: 0}, op.Const{Value: 1}}}, Fitness: 1},
+			{Genome: &Program{Op: op.Var{Index: 0}}, Fitness: 1},
+		}
+		sel = selDoubleTournament{nContestants: 1, sizePressure: 2, gp: gp}
+	)
+	_, indexes, err := sel.Apply(200, indis, rng)
+	if err != nil {
+		t.Errorf("Expected nil, got %s", err)
+		return
+	}
+	// The largest individual can only be selected if it wins both fitness
+	// tournaments, which happens a quarter of the time
+	var nSmallest int
+	for _, idx := range indexes {
+		if idx == 1 {
+			nSmallest++
+		}
+	}
+	if nSmallest < 125 {
+		t.Errorf("Expected the smallest individual to be selected most of the time, got %d/200", nSmallest)
+	}
+	if err := (selDoubleTournament{nContestants: 3, sizePressure: 2.5}).Validate(); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
+
+func TestGPSelection(t *testing.T) {
+	var (
+		X = [][]float64{
+			[]float64{1, 2, 3, 4, 5},
+			[]float64{4, 5, 6, 7, 8},
+		}
+		Y = []float64{7, 13, 21, 31, 43}
+	)
+	for _, selection := range []string{"tournament", "lexicase", "eps_lexicase", "double_tournament"} {
+		t.Run(selection, func(t *testing.T) {
+			var conf = NewDefaultGPConfig()
+			conf.RNG = rand.New(rand.NewSource(42))
+			conf.NIndividuals = 30
+			conf.NGenerations = 5
+			conf.PolishBest = false
+			conf.Selection = selection
+			var gp, err = conf.NewGP()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if _, err = gp.BestProgram(); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+			}
+		})
+	}
+}
+
+func TestGPSelectionErrors(t *testing.T) {
+	var testCases = []func(conf *GPConfig){
+		func(conf *GPConfig) { conf.Selection = "roulette" },
+		func(conf *GPConfig) {
+			conf.Selection = "lexicase"
+			conf.LossMetric = metrics.F1{}
+		},
+		func(conf *GPConfig) {
+			conf.Selection = "double_tournament"
+			conf.SizePressure = 3
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var conf = NewDefaultGPConfig()
+			tc(&conf)
+			if _, err := conf.NewGP(); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package xgp
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/MaxHalford/eaopt"
+
+	"github.com/MaxHalford/xgp/op"
+)
+
+// GenerationStats contains statistics about the state of the GA after a
+// generation. The fitnesses are those of the individuals of every population;
+// the scores are obtained with the EvalMetric by the best Program found so
+// far. ValScore is NaN if no validation set is provided.
+type GenerationStats struct {
+	Generation    uint
+	Duration      time.Duration
+	NEvaluations  uint64
+	BestFitness   float64
+	MedianFitness float64
+	WorstFitness  float64
+	MeanSize      float64
+	MeanHeight    float64
+	NDistinct     int
+	TrainScore    float64
+	ValScore      float64
+}
+
+// genomeShape returns the number of operators and the height of a Program or
+// of a MultiProgram. The size of a MultiProgram is the sum of the sizes of
+// it's Operators whilst it's height is the largest one.
+func genomeShape(genome eaopt.Genome) (size, height uint) {
+	var ops []op.Operator
+	switch genome := genome.(type) {
+	case *Program:
+		ops = []op.Operator{genome.Op}
+	case *MultiProgram:
+		ops = genome.Ops
+	}
+	for _, operator := range ops {
+		size += op.CountOps(operator)
+		if h := op.CalcHeight(operator); h > height {
+			height = h
+		}
+	}
+	return
+}
+
+// stats computes the GenerationStats of the current state of a GA.
+func (gp *GP) stats(ga *eaopt.GA) (GenerationStats, error) {
+	var (
+		stats = GenerationStats{
+			Generation:   ga.Generations,
+			Duration:     time.Since(gp.start),
+			NEvaluations: gp.NEvaluations(),
+		}
+		fitnesses []float64
+		distinct  = make(map[string]bool)
+	)
+	for _, pop := range ga.Populations {
+		for _, indi := range pop.Individuals {
+			var size, height = genomeShape(indi.Genome)
+			stats.MeanSize += float64(size)
+			stats.MeanHeight += float64(height)
+			distinct[fmt.Sprint(indi.Genome)] = true
+			fitnesses = append(fitnesses, indi.Fitness)
+		}
+	}
+	if len(fitnesses) > 0 {
+		stats.MeanSize /= float64(len(fitnesses))
+		stats.MeanHeight /= float64(len(fitnesses))
+		sort.Float64s(fitnesses)
+		stats.BestFitness = fitnesses[0]
+		stats.WorstFitness = fitnesses[len(fitnesses)-1]
+		stats.MedianFitness = median(fitnesses)
+	}
+	stats.NDistinct = len(distinct)
+	var err error
+	stats.TrainScore, stats.ValScore, err = gp.scores()
+	return stats, err
+}
+
+// reportStats calls the Callback with the statistics of the current
+// generation, if there is a Callback.
+func (gp *GP) reportStats(ga *eaopt.GA) {
+	if gp.Callback == nil || gp.fitErr != nil {
+		return
+	}
+	stats, err := gp.stats(ga)
+	if err != nil {
+		gp.fitErr = err
+		return
+	}
+	gp.Callback(stats)
+}
@@ -0,0 +1,141 @@
+package xgp
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/MaxHalford/eaopt"
+)
+
+// rowFraction returns the fraction of the training rows on which the
+// Programs are evaluated during a generation, the initial populations being
+// generation 0.
+func (gp GP) rowFraction(generation uint) float64 {
+	return math.Min(1, gp.RowFraction*math.Pow(gp.RowFractionGrowth, float64(generation)))
+}
+
+// sampled indicates if the Programs are evaluated on a subset of the
+// training rows.
+func (gp GP) sampled() bool {
+	return len(gp.Y) < len(gp.yFull)
+}
+
+// sampleRows sets the training set to a random subset of the rows of the
+// full training set, according to the fraction of rows of a generation. It
+// returns false if the training set is left unchanged, which happens once
+// all the rows are used.
+func (gp *GP) sampleRows(generation uint, rng *rand.Rand) bool {
+	var (
+		n = len(gp.yFull)
+		k = int(math.Ceil(gp.rowFraction(generation) * float64(n)))
+	)
+	if k >= n {
+		if !gp.sampled() {
+			return false
+		}
+		gp.useRows(nil)
+		return true
+	}
+	if k < 1 {
+		k = 1
+	}
+	var rows = rng.Perm(n)[:k]
+	sort.Ints(rows)
+	gp.useRows(rows)
+	return true
+}
+
+// useRows sets the training set to the given rows of the full training set,
+// all the rows being used if rows is nil. The rows are kept so that they can
+// be checkpointed.
+func (gp *GP) useRows(rows []int) {
+	gp.rows = rows
+	if rows == nil {
+		gp.X, gp.Y, gp.W = gp.xFull, gp.yFull, gp.wFull
+		return
+	}
+	var k = len(rows)
+	gp.X = make([][]float64, len(gp.xFull))
+	for j, x := range gp.xFull {
+		gp.X[j] = make([]float64, k)
+		for i, row := range rows {
+			gp.X[j][i] = x[row]
+		}
+	}
+	gp.Y = make([]float64, k)
+	for i, row := range rows {
+		gp.Y[i] = gp.yFull[row]
+	}
+	gp.W = nil
+	if gp.wFull != nil {
+		gp.W = make([]float64, k)
+		for i, row := range rows {
+			gp.W[i] = gp.wFull[row]
+		}
+	}
+}
+
+// resample draws the training rows of the next generation. The fitnesses
+// obtained on the previous rows are outdated, hence the hall of fame is
+// evaluated again and the individuals of the populations are marked as not
+// evaluated so that their offsprings are evaluated on the new rows.
+func (gp *GP) resample(ga *eaopt.GA) error {
+	if !gp.sampleRows(ga.Generations+1, gp.RNG) {
+		return nil
+	}
+	gp.prepareTrain()
+	if err := reevaluate(ga.HallOfFame); err != nil {
+		return err
+	}
+	for _, pop := range ga.Populations {
+		for i := range pop.Individuals {
+			pop.Individuals[i].Evaluated = false
+		}
+	}
+	return nil
+}
+
+// rescore evaluates the hall of fame and the populations on the full
+// training set if the last generation only used a subset of the rows.
+func (gp *GP) rescore(ga *eaopt.GA) error {
+	if !gp.sampled() {
+		return nil
+	}
+	gp.useRows(nil)
+	gp.prepareTrain()
+	if err := reevaluate(ga.HallOfFame); err != nil {
+		return err
+	}
+	for _, pop := range ga.Populations {
+		if err := reevaluate(pop.Individuals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reevaluate evaluates Individuals in parallel regardless of whether they
+// have already been evaluated and then sorts them by fitness. Individuals
+// without a Genome, which can occur in the hall of fame, are left untouched.
+func reevaluate(indis eaopt.Individuals) error {
+	var toEvaluate = make(eaopt.Individuals, 0, len(indis))
+	for _, indi := range indis {
+		if indi.Genome != nil {
+			indi.Evaluated = false
+			toEvaluate = append(toEvaluate, indi)
+		}
+	}
+	if err := evaluateParallel(toEvaluate); err != nil {
+		return err
+	}
+	var i int
+	for j := range indis {
+		if indis[j].Genome != nil {
+			indis[j] = toEvaluate[i]
+			i++
+		}
+	}
+	indis.SortByFitness()
+	return nil
+}
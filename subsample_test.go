@@ -0,0 +1,127 @@
+package xgp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestRowFraction(t *testing.T) {
+	var testCases = []struct {
+		fraction   float64
+		growth     float64
+		generation uint
+		output     float64
+	}{
+		{1, 1, 0, 1},
+		{0.5, 1, 3, 0.5},
+		{0.25, 2, 0, 0.25},
+		{0.25, 2, 1, 0.5},
+		{0.25, 2, 3, 1},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var gp = GP{GPConfig: GPConfig{RowFraction: tc.fraction, RowFractionGrowth: tc.growth}}
+			if output := gp.rowFraction(tc.generation); output != tc.output {
+				t.Errorf("Expected %f, got %f", tc.output, output)
+			}
+		})
+	}
+}
+
+func TestSampleRows(t *testing.T) {
+	var gp = GP{GPConfig: GPConfig{RowFraction: 0.3, RowFractionGrowth: 2}}
+	gp.xFull = [][]float64{
+		[]float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		[]float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90},
+	}
+	gp.yFull = []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	gp.wFull = []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	gp.X, gp.Y, gp.W = gp.xFull, gp.yFull, gp.wFull
+	var rng = rand.New(rand.NewSource(42))
+	for generation, size := range []int{3, 6, 10} {
+		if !gp.sampleRows(uint(generation), rng) {
+			t.Errorf("Expected true, got false")
+		}
+		if len(gp.Y) != size || len(gp.W) != size || len(gp.X[0]) != size {
+			t.Errorf("Expected %d rows, got %d", size, len(gp.Y))
+		}
+		for i, y := range gp.Y {
+			if gp.X[0][i] != y || gp.X[1][i] != 10*y || gp.W[i] != y {
+				t.Errorf("Row %d is not aligned", i)
+			}
+			if i > 0 && y <= gp.Y[i-1] {
+				t.Errorf("Rows are not ordered")
+			}
+		}
+	}
+	if gp.sampleRows(3, rng) {
+		t.Errorf("Expected false, got true")
+	}
+}
+
+func TestGPRowFraction(t *testing.T) {
+	var (
+		rng = rand.New(rand.NewSource(42))
+		X   = [][]float64{make([]float64, 50), make([]float64, 50)}
+		Y   = make([]float64, 50)
+	)
+	for i := range Y {
+		X[0][i] = rng.Float64()
+		X[1][i] = rng.Float64()
+		Y[i] = X[0][i]*X[0][i] + X[1][i] + 0.1*rng.NormFloat64()
+	}
+	for i, multiObjective := range []bool{false, true} {
+		t.Run(fmt.Sprintf("TC %d", i), func(t *testing.T) {
+			var conf = NewDefaultGPConfig()
+			conf.RNG = rand.New(rand.NewSource(42))
+			conf.NIndividuals = 30
+			conf.NGenerations = 5
+			conf.HofSize = 3
+			conf.RowFraction = 0.2
+			conf.RowFractionGrowth = 1.2
+			conf.PolishBest = false
+			conf.MultiObjective = multiObjective
+			var gp, err = conf.NewGP()
+			if err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if err = gp.Fit(X, Y, nil, nil, nil, nil, false); err != nil {
+				t.Errorf("Expected nil, got %s", err)
+				return
+			}
+			if len(gp.Y) != len(Y) {
+				t.Errorf("Expected %d rows, got %d", len(Y), len(gp.Y))
+			}
+			// The hall of fame has to be scored on the full training set
+			for _, indi := range gp.GA.HallOfFame {
+				if indi.Genome == nil {
+					continue
+				}
+				var prog = indi.Genome.Clone().(*Program)
+				fitness, err := prog.evaluate()
+				if err != nil {
+					t.Errorf("Expected nil, got %s", err)
+				}
+				if math.Abs(fitness-indi.Fitness) > 1e-12 {
+					t.Errorf("Expected %f, got %f", fitness, indi.Fitness)
+				}
+			}
+		})
+	}
+}
+
+func TestGPRowFractionErrors(t *testing.T) {
+	var conf = NewDefaultGPConfig()
+	conf.RowFraction = 1.5
+	if _, err := conf.NewGP(); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	conf = NewDefaultGPConfig()
+	conf.RowFractionGrowth = 0.5
+	if _, err := conf.NewGP(); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sort"
 	"time"
+
+	"github.com/MaxHalford/eaopt"
 )
 
 // newRand returns a new random number generator with a random seed.
@@ -41,6 +44,23 @@ func countDistinct(x []float64) int {
 	return len(seen)
 }
 
+// sortedDistinct returns the unique elements of a slice of float64s in
+// increasing order.
+func sortedDistinct(x []float64) []float64 {
+	var (
+		seen     = make(map[float64]bool)
+		distinct = make([]float64, 0)
+	)
+	for _, xi := range x {
+		if !seen[xi] {
+			seen[xi] = true
+			distinct = append(distinct, xi)
+		}
+	}
+	sort.Float64s(distinct)
+	return distinct
+}
+
 // fmtDuration the "hours:minutes:seconds" representation of a time.Duration.
 func fmtDuration(d time.Duration) string {
 	d = d.Round(time.Second)
@@ -51,3 +71,36 @@ func fmtDuration(d time.Duration) string {
 	s := d / time.Second
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
 }
+
+// updateHallOfFame inserts an Individual into the hall of fame of a GA if it
+// is better than one of the hall of fame's members.
+func updateHallOfFame(ga *eaopt.GA, indi eaopt.Individual, rng *rand.Rand) {
+	for i, champ := range ga.HallOfFame {
+		if indi.Fitness < champ.Fitness {
+			copy(ga.HallOfFame[i+1:], ga.HallOfFame[i:])
+			ga.HallOfFame[i] = indi.Clone(rng)
+			return
+		}
+	}
+}
+
+// float32s converts a slice to single precision, nil is returned if x is nil.
+func float32s(x []float64) []float32 {
+	if x == nil {
+		return nil
+	}
+	var x32 = make([]float32, len(x))
+	for i, xi := range x {
+		x32[i] = float32(xi)
+	}
+	return x32
+}
+
+// float64s converts a slice to double precision.
+func float64s(x []float32) []float64 {
+	var x64 = make([]float64, len(x))
+	for i, xi := range x {
+		x64[i] = float64(xi)
+	}
+	return x64
+}